@@ -0,0 +1,355 @@
+// Package client is the Go client of a go-paxos cluster. It discovers the replicas of the cluster, spreads requests
+// among them and retries failed requests with an idempotency key so that a value is applied only once. An attempt
+// which is decided again after an earlier one is kept in the log as a duplicate entry.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-paxos/domain"
+	"github.com/google/uuid"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config contains the settings of the client where zero values are replaced by the defaults
+type Config struct {
+	Seeds        []string      // addresses of the nodes used to discover the replicas of the cluster
+	Timeout      time.Duration // timeout of each attempt of a request (default: 30s)
+	Attempts     int           // number of attempts of a request including the first one (default: 3)
+	Backoff      time.Duration // delay before the second attempt which is doubled for each attempt (default: 100ms)
+	MaxIdleConns int           // number of idle connections kept open to each replica (default: 16)
+}
+
+// Result describes a value decided by the cluster
+type Result struct {
+	Slot      int    // slot of the replicated log in which the value was decided
+	Val       string // decided value
+	Duplicate bool   // the value was decided by an earlier attempt with the same idempotency key
+	Fast      bool   // the value was decided in a fast round without going through a leader
+	Replica   string // replica which responded to the request
+	Attempts  int    // number of attempts made until the value was decided
+}
+
+// Entry is a value decided in a slot of the replicated log
+type Entry struct {
+	Slot int
+	Key  string // idempotency key of the request which decided the value (if any)
+	Val  string
+	// the value was decided again by another attempt with the same idempotency key and should be skipped
+	Duplicate bool
+}
+
+type Client struct {
+	conf      Config
+	replicas  []string
+	next      uint32 // index of the replica which receives the next request
+	http      *http.Client
+	transport *http.Transport
+	lock      *sync.RWMutex
+}
+
+// New creates a client and discovers the replicas of the cluster through the seed nodes
+func New(ctx context.Context, conf Config) (*Client, error) {
+	if conf.Timeout <= 0 {
+		conf.Timeout = 30 * time.Second
+	}
+	if conf.Attempts <= 0 {
+		conf.Attempts = 3
+	}
+	if conf.Backoff <= 0 {
+		conf.Backoff = 100 * time.Millisecond
+	}
+	if conf.MaxIdleConns <= 0 {
+		conf.MaxIdleConns = 16
+	}
+
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		MaxIdleConns:        conf.MaxIdleConns * len(conf.Seeds),
+		MaxIdleConnsPerHost: conf.MaxIdleConns,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	c := &Client{
+		conf:      conf,
+		http:      &http.Client{Transport: transport},
+		transport: transport,
+		lock:      &sync.RWMutex{},
+	}
+
+	if err := c.Discover(ctx); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Replicas returns the replicas which the client currently sends requests to
+func (c *Client) Replicas() []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return append([]string{}, c.replicas...)
+}
+
+// Write gets the value decided by the cluster. The request is retried on retryable failures with an idempotency key
+// generated for the value.
+func (c *Client) Write(ctx context.Context, val string) (Result, error) {
+	return c.WriteWithKey(ctx, uuidKey(), val)
+}
+
+func uuidKey() string {
+	return uuid.New().String()
+}
+
+// WriteWithKey gets the value decided by the cluster using the given idempotency key, which should be unique for
+// each value and the same for every attempt of it (including the attempts made by the caller after a failure)
+func (c *Client) WriteWithKey(ctx context.Context, key, val string) (Result, error) {
+	return c.write(ctx, key, val, noSlot)
+}
+
+// Read returns the entries decided within the slot range [from, to) as applied by one of the replicas. The range is
+// truncated to the entries applied by the replica and to is ignored if it is negative.
+func (c *Client) Read(ctx context.Context, from, to int) ([]Entry, error) {
+	entries, _, err := c.read(ctx, from, to, noSlot)
+	return entries, err
+}
+
+func (c *Client) write(ctx context.Context, key, val string, minSlot int) (Result, error) {
+	var res Result
+	attempts, err := c.retry(ctx, func(replica string) *Error {
+		var dec domain.Result
+		_, err := c.do(ctx, http.MethodPost, replica, domain.RequestReplicaEndpoint, minSlot, func(req *http.Request) {
+			req.Header.Set(`Content-Type`, `text/plain`)
+			req.Header.Set(domain.IdempotencyKeyHeader, key)
+		}, bytes.NewBufferString(val), &dec)
+		if err != nil {
+			return err
+		}
+
+		res = Result{Slot: dec.SlotID, Val: dec.Val, Duplicate: dec.Duplicate, Fast: dec.Fast, Replica: replica}
+		return nil
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	res.Attempts = attempts
+	return res, nil
+}
+
+// read returns the entries within the range along with the last slot applied by the replica which served them
+func (c *Client) read(ctx context.Context, from, to, minSlot int) ([]Entry, int, error) {
+	endpoint := domain.LogReplicaEndpoint + `?from=` + strconv.Itoa(from)
+	if to >= 0 {
+		endpoint += `&to=` + strconv.Itoa(to)
+	}
+
+	var entries []Entry
+	var applied int
+	_, err := c.retry(ctx, func(replica string) *Error {
+		var log []domain.Entry
+		res, err := c.do(ctx, http.MethodGet, replica, endpoint, minSlot, nil, nil, &log)
+		if err != nil {
+			return err
+		}
+
+		applied, _ = strconv.Atoi(res.Header.Get(domain.SlotHeader))
+		entries = make([]Entry, 0, len(log))
+		for _, e := range log {
+			entries = append(entries, Entry{Slot: e.SlotID, Key: e.Key, Val: e.Val, Duplicate: e.Duplicate})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entries, applied, nil
+}
+
+// retry makes attempts on the replicas in turn until an attempt succeeds, a non-retryable error is returned or the
+// configured number of attempts is exceeded. The number of attempts made is returned.
+func (c *Client) retry(ctx context.Context, attempt func(replica string) *Error) (int, error) {
+	backoff := c.conf.Backoff
+	var err *Error
+	for i := 1; i <= c.conf.Attempts; i++ {
+		replica, ok := c.pick()
+		if !ok {
+			return i, &Error{Kind: KindUnavailable, Message: errNoReplicas}
+		}
+
+		err = attempt(replica)
+		if err == nil {
+			return i, nil
+		}
+
+		if !err.Retryable() || i == c.conf.Attempts {
+			break
+		}
+
+		// the membership may have changed if the replica could not be reached
+		if err.Kind == KindUnavailable {
+			_ = c.Discover(ctx)
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return i, &Error{Kind: KindCanceled, Replica: replica, Message: ctx.Err().Error()}
+		}
+		backoff *= 2
+	}
+
+	return c.conf.Attempts, err
+}
+
+// do sends a single attempt of a request to the given replica and decodes the JSON response into v
+func (c *Client) do(ctx context.Context, method, replica, endpoint string, minSlot int, prepare func(*http.Request), body io.Reader, v interface{}) (*http.Response, *Error) {
+	attemptCtx, cancel := context.WithTimeout(ctx, c.conf.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(attemptCtx, method, `http://`+replica+endpoint, body)
+	if err != nil {
+		return nil, &Error{Kind: KindRejected, Replica: replica, Message: err.Error()}
+	}
+	// the replica and the leaders stop working on the request once the attempt has timed out
+	domain.InjectTimeout(attemptCtx, req)
+	if minSlot != noSlot {
+		req.Header.Set(domain.MinSlotHeader, strconv.Itoa(minSlot))
+	}
+	if prepare != nil {
+		prepare(req)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		switch {
+		case ctx.Err() != nil:
+			return nil, &Error{Kind: KindCanceled, Replica: replica, Message: ctx.Err().Error()}
+		case attemptCtx.Err() != nil:
+			return nil, &Error{Kind: KindTimeout, Replica: replica, Message: err.Error()}
+		}
+		return nil, &Error{Kind: KindUnavailable, Replica: replica, Message: err.Error()}
+	}
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, &Error{Kind: KindUnavailable, Replica: replica, Message: err.Error()}
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, &Error{Kind: kind(res.StatusCode), Replica: replica, Status: res.StatusCode, Message: string(data)}
+	}
+
+	if err = json.Unmarshal(data, v); err != nil {
+		return nil, &Error{Kind: KindInternal, Replica: replica, Status: res.StatusCode, Message: err.Error()}
+	}
+
+	return res, nil
+}
+
+// kind maps the status of a failed response to the kind of the error
+func kind(status int) Kind {
+	switch status {
+	case http.StatusTooManyRequests:
+		return KindRateLimited
+	case http.StatusServiceUnavailable:
+		return KindDraining
+	case http.StatusGatewayTimeout:
+		// the replica has not applied the requested slot yet, which another replica may have
+		return KindTimeout
+	case http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusMethodNotAllowed:
+		return KindRejected
+	}
+	return KindInternal
+}
+
+// pick returns the replica which receives the next request so that requests are spread among the replicas
+func (c *Client) pick() (string, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if len(c.replicas) == 0 {
+		return ``, false
+	}
+
+	i := atomic.AddUint32(&c.next, 1)
+	return c.replicas[int(i)%len(c.replicas)], true
+}
+
+// Discover refreshes the replicas from the membership known to the first reachable node among the seeds and the
+// replicas discovered earlier
+func (c *Client) Discover(ctx context.Context) error {
+	candidates := append(c.Replicas(), c.conf.Seeds...)
+	var lastErr error
+	for _, node := range candidates {
+		replicas, err := c.discover(ctx, node)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		c.lock.Lock()
+		c.replicas = replicas
+		c.lock.Unlock()
+		return nil
+	}
+
+	if lastErr == nil {
+		lastErr = errors.New(errNoReplicas)
+	}
+	return &Error{Kind: KindUnavailable, Message: fmt.Sprintf(`%s: %s`, errDiscovery, lastErr)}
+}
+
+// discover returns the replicas of the configuration which decides the next slot of the given node
+func (c *Client) discover(ctx context.Context, node string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.conf.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, `http://`+node+domain.MembershipEndpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.New(fmt.Sprintf(`%s responded with status %d`, node, res.StatusCode))
+	}
+
+	var membership domain.Membership
+	if err = json.NewDecoder(res.Body).Decode(&membership); err != nil {
+		return nil, err
+	}
+
+	var replicas []string
+	for _, n := range membership.Current.Nodes {
+		if n.Role == domain.RoleReplica {
+			replicas = append(replicas, n.Address)
+		}
+	}
+
+	if len(replicas) == 0 {
+		return nil, errors.New(fmt.Sprintf(`%s (node: %s)`, errNoReplicas, node))
+	}
+
+	return replicas, nil
+}
+
+// Close releases the idle connections kept by the client
+func (c *Client) Close() {
+	c.transport.CloseIdleConnections()
+}
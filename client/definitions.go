@@ -0,0 +1,9 @@
+package client
+
+// noSlot is used as the minimum slot of requests which do not require any slot to be applied
+const noSlot = -1
+
+const (
+	errNoReplicas = `no replicas are known to the client`
+	errDiscovery  = `discovering replicas failed`
+)
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kind classifies the errors returned by the client so that callers can decide how to react without parsing messages
+type Kind string
+
+const (
+	KindUnavailable Kind = `unavailable`  // replica could not be reached
+	KindTimeout     Kind = `timeout`      // attempt did not complete within the configured timeout
+	KindRateLimited Kind = `rate_limited` // replica rejected the request as it exceeded the rate limit
+	KindDraining    Kind = `draining`     // replica is shutting down and does not admit new requests
+	KindInternal    Kind = `internal`     // replica failed to get the value decided
+	KindRejected    Kind = `rejected`     // request is invalid or not accepted by the node (eg: a learner)
+	KindCanceled    Kind = `canceled`     // context of the request was canceled by the caller
+)
+
+// Error is returned by every operation of the client and carries the kind of the failure along with the replica and
+// the http status (if a response was received) of the last attempt
+type Error struct {
+	Kind    Kind
+	Replica string
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Status != 0 {
+		return fmt.Sprintf(`%s error from %s (status: %d): %s`, e.Kind, e.Replica, e.Status, e.Message)
+	}
+	return fmt.Sprintf(`%s error from %s: %s`, e.Kind, e.Replica, e.Message)
+}
+
+// Retryable checks if the request may succeed when it is sent again, possibly to a different replica
+func (e *Error) Retryable() bool {
+	return e.Kind != KindRejected && e.Kind != KindCanceled
+}
+
+// IsKind checks if the error was returned by the client with given kind
+func IsKind(err error, kind Kind) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Kind == kind
+}
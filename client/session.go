@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Session tracks the highest slot observed through it and sends that slot as the consistency token of every request,
+// so that a replica serves the request only after applying it. This gives read-your-writes and monotonic reads even
+// when the requests of the session are served by different replicas.
+type Session struct {
+	client *Client
+	slot   int64
+}
+
+// NewSession creates a session which has not observed any slot yet
+func (c *Client) NewSession() *Session {
+	return &Session{client: c, slot: noSlot}
+}
+
+// Slot returns the highest slot observed by the session which can be passed to another session with Observe
+func (s *Session) Slot() int {
+	return int(atomic.LoadInt64(&s.slot))
+}
+
+// Observe advances the session to the given slot if it has not observed a higher one
+func (s *Session) Observe(slot int) {
+	for {
+		current := atomic.LoadInt64(&s.slot)
+		if int64(slot) <= current || atomic.CompareAndSwapInt64(&s.slot, current, int64(slot)) {
+			return
+		}
+	}
+}
+
+// Write gets the value decided once the serving replica has applied every slot observed by the session
+func (s *Session) Write(ctx context.Context, val string) (Result, error) {
+	res, err := s.client.write(ctx, uuidKey(), val, s.Slot())
+	if err != nil {
+		return Result{}, err
+	}
+
+	s.Observe(res.Slot)
+	return res, nil
+}
+
+// Read returns the entries within the slot range [from, to) from a replica which has applied every slot observed by
+// the session
+func (s *Session) Read(ctx context.Context, from, to int) ([]Entry, error) {
+	entries, applied, err := s.client.read(ctx, from, to, s.Slot())
+	if err != nil {
+		return nil, err
+	}
+
+	s.Observe(applied)
+	return entries, nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-paxos/domain"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	httpClient = &http.Client{Timeout: 10 * time.Second}
+	// streams are long-lived and rely on heartbeats instead of a timeout
+	streamClient = &http.Client{}
+)
+
+func status(node string) (domain.Status, error) {
+	var st domain.Status
+	err := get(node, domain.StatusEndpoint, &st)
+	return st, err
+}
+
+// ready returns an empty reason if the node is ready or the reason reported by the node otherwise
+func ready(node string) (string, error) {
+	res, err := httpClient.Get(`http://` + node + domain.ReadyEndpoint)
+	if err != nil {
+		return ``, err
+	}
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return ``, err
+	}
+
+	if res.StatusCode == http.StatusOK {
+		return ``, nil
+	}
+	return string(data), nil
+}
+
+func replicaLog(node string, from, to int) ([]domain.Entry, error) {
+	var entries []domain.Entry
+	endpoint := domain.LogReplicaEndpoint + `?from=` + strconv.Itoa(from)
+	if to >= 0 {
+		endpoint += `&to=` + strconv.Itoa(to)
+	}
+
+	err := get(node, endpoint, &entries)
+	return entries, err
+}
+
+func get(node, endpoint string, v interface{}) error {
+	res, err := httpClient.Get(`http://` + node + endpoint)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return errors.New(fmt.Sprintf(`%s responded with status %d for %s %s`, node, res.StatusCode, endpoint, string(data)))
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func post(node, endpoint string, body interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	res, err := httpClient.Post(`http://`+node+endpoint, `application/json`, bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		data, _ = ioutil.ReadAll(res.Body)
+		return errors.New(fmt.Sprintf(`%s responded with status %d for %s %s`, node, res.StatusCode, endpoint, string(data)))
+	}
+
+	return nil
+}
+
+func reloadConfigs(node string) (domain.ReloadResult, error) {
+	var result domain.ReloadResult
+	res, err := httpClient.Post(`http://`+node+domain.ReloadEndpoint, `application/json`, nil)
+	if err != nil {
+		return result, err
+	}
+	defer res.Body.Close()
+
+	err = json.NewDecoder(res.Body).Decode(&result)
+	if err != nil {
+		return result, errors.New(fmt.Sprintf(`%s responded with status %d for %s`, node, res.StatusCode, domain.ReloadEndpoint))
+	}
+
+	if result.Error != `` {
+		return result, errors.New(fmt.Sprintf(`%s refused to reload: %s`, node, result.Error))
+	}
+	return result, nil
+}
+
+// follow reads the decision stream of a replica starting from the given slot and passes each entry to the handler
+// until the stream is interrupted. The slot is sent as Last-Event-ID so that the replica resumes after it.
+func follow(node string, from int, handle func(domain.Entry)) error {
+	req, err := http.NewRequest(http.MethodGet, `http://`+node+domain.StreamReplicaEndpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(`Accept`, `text/event-stream`)
+	req.Header.Set(`Last-Event-ID`, strconv.Itoa(from-1))
+
+	res, err := streamClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return errors.New(fmt.Sprintf(`%s responded with status %d for %s`, node, res.StatusCode, domain.StreamReplicaEndpoint))
+	}
+
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, `data: `) {
+			continue
+		}
+
+		var entry domain.Entry
+		if err = json.Unmarshal([]byte(strings.TrimPrefix(line, `data: `)), &entry); err != nil {
+			return err
+		}
+		handle(entry)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
@@ -0,0 +1,459 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/go-paxos/domain"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const usage = `usage: paxosctl -nodes <host:port,...> <command> [arguments]
+
+commands:
+  members                           lists the nodes along with their role, readiness and state
+  log <replica> [from] [to]         dumps the applied log entries of a replica within [from, to)
+  stream <replica> [from]           follows the decided entries of a replica from the given slot, resuming on reconnects
+  compare                           compares the logs of the given replicas and reports divergent slots
+  transfer-leader <leader id>       makes the given leader the preferred leader of the given replicas
+  set-address <id> <address>        updates the address of a node in the address books of the given nodes
+  log-level <level>                 changes the log level of the given nodes (ERROR, WARN, INFO, DEBUG, TRACE)
+  membership                        shows the current and pending configurations of the cluster known to the first node
+  add-node <id> <role> <address> [weight]
+                                    adds a node to the cluster through consensus (the first node should be a replica)
+  remove-node <id>                  removes a node from the cluster through consensus (the first node should be a replica)
+  reload                            reloads the runtime-tunable configurations of the given nodes from their files
+  drain [-terminate] [-timeout d]   stops the given nodes from admitting requests and waits for in-flight requests
+  terminate                         terminates the given nodes
+`
+
+func main() {
+	nodesFlag := flag.String(`nodes`, os.Getenv(`PAXOS_NODES`), `comma separated list of nodes (defaults to PAXOS_NODES)`)
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	nodes := hosts(*nodesFlag)
+	var err error
+	switch args[0] {
+	case `members`:
+		err = members(nodes)
+	case `log`:
+		err = dumpLog(args[1:])
+	case `stream`:
+		err = stream(args[1:])
+	case `compare`:
+		err = compare(nodes)
+	case `transfer-leader`:
+		err = transferLeader(nodes, args[1:])
+	case `set-address`:
+		err = setAddress(nodes, args[1:])
+	case `log-level`:
+		err = logLevel(nodes, args[1:])
+	case `membership`:
+		err = membership(nodes)
+	case `add-node`:
+		err = addNode(nodes, args[1:])
+	case `remove-node`:
+		err = removeNode(nodes, args[1:])
+	case `reload`:
+		err = reload(nodes)
+	case `drain`:
+		err = drain(nodes, args[1:])
+	case `terminate`:
+		err = terminate(nodes)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func hosts(arg string) []string {
+	var list []string
+	for _, host := range strings.Split(arg, `,`) {
+		if host = strings.TrimSpace(host); host != `` {
+			list = append(list, host)
+		}
+	}
+
+	return list
+}
+
+func requireNodes(nodes []string) error {
+	if len(nodes) == 0 {
+		return errors.New(`node list is empty (use -nodes or PAXOS_NODES)`)
+	}
+	return nil
+}
+
+func members(nodes []string) error {
+	if err := requireNodes(nodes); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tID\tROLE\tREADY\tDRAINING\tIN-FLIGHT\tSTATE")
+	for _, node := range nodes {
+		st, err := status(node)
+		if err != nil {
+			fmt.Fprintf(w, "%s\t-\t-\tunreachable\t-\t-\t%s\n", node, err)
+			continue
+		}
+
+		readiness := `yes`
+		reason, err := ready(node)
+		if err != nil {
+			readiness = `unknown`
+		} else if reason != `` {
+			readiness = `no (` + reason + `)`
+		}
+
+		var state string
+		if st.Leader != nil {
+			state = fmt.Sprintf(`ballot=%d last_slot=%d`, st.Leader.Ballot, st.Leader.LastSlot)
+		}
+		if st.Replica != nil {
+			state = fmt.Sprintf(`log_length=%d pending=%d preferred_leader=%d`, st.Replica.LogLength, st.Replica.PendingLogSize, st.Replica.PreferredLeader)
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%t\t%d\t%s\n", node, st.ID, st.Role, readiness, st.Draining, st.InFlight, state)
+	}
+
+	return w.Flush()
+}
+
+func dumpLog(args []string) error {
+	if len(args) == 0 {
+		return errors.New(`replica is required: paxosctl log <replica> [from] [to]`)
+	}
+
+	from, to := 0, -1
+	var err error
+	if len(args) > 1 {
+		if from, err = strconv.Atoi(args[1]); err != nil {
+			return err
+		}
+	}
+	if len(args) > 2 {
+		if to, err = strconv.Atoi(args[2]); err != nil {
+			return err
+		}
+	}
+
+	entries, err := replicaLog(args[0], from, to)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		fmt.Printf("%d\t%s\n", entry.SlotID, entry.Val)
+	}
+	return nil
+}
+
+func stream(args []string) error {
+	if len(args) < 1 {
+		return errors.New(`replica is required: paxosctl stream <replica> [from]`)
+	}
+
+	next := 0
+	if len(args) > 1 {
+		var err error
+		if next, err = strconv.Atoi(args[1]); err != nil {
+			return err
+		}
+	}
+
+	for {
+		err := follow(args[0], next, func(entry domain.Entry) {
+			fmt.Printf("%d\t%s\n", entry.SlotID, entry.Val)
+			next = entry.SlotID + 1
+		})
+		log.Printf("stream of %s interrupted at slot %d (%v), reconnecting", args[0], next, err)
+		time.Sleep(time.Second)
+	}
+}
+
+func compare(nodes []string) error {
+	if len(nodes) < 2 {
+		return errors.New(`at least two replicas are required to compare logs`)
+	}
+
+	logs := make([][]domain.Entry, len(nodes))
+	for i, node := range nodes {
+		entries, err := replicaLog(node, 0, -1)
+		if err != nil {
+			return err
+		}
+		logs[i] = entries
+		fmt.Printf("%s: %d entries\n", node, len(entries))
+	}
+
+	diverged := 0
+	for slot := 0; ; slot++ {
+		var val string
+		found, conflict := false, false
+		for _, entries := range logs {
+			if slot >= len(entries) {
+				continue
+			}
+			if !found {
+				val, found = entries[slot].Val, true
+				continue
+			}
+			if entries[slot].Val != val {
+				conflict = true
+			}
+		}
+
+		if !found {
+			break
+		}
+
+		if conflict {
+			diverged++
+			fmt.Printf("slot %d diverged:", slot)
+			for i, entries := range logs {
+				if slot < len(entries) {
+					fmt.Printf(" %s=%q", nodes[i], entries[slot].Val)
+				}
+			}
+			fmt.Println()
+		}
+	}
+
+	if diverged > 0 {
+		return errors.New(fmt.Sprintf(`%d divergent slots found`, diverged))
+	}
+
+	fmt.Println(`logs are consistent`)
+	return nil
+}
+
+func transferLeader(nodes, args []string) error {
+	if len(args) != 1 {
+		return errors.New(`leader id is required: paxosctl transfer-leader <leader id>`)
+	}
+	if err := requireNodes(nodes); err != nil {
+		return err
+	}
+
+	leader, err := strconv.Atoi(args[0])
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		if err := post(node, domain.PreferLeaderEndpoint, domain.LeaderPreference{Leader: leader}); err != nil {
+			return err
+		}
+		fmt.Printf("%s now forwards requests to leader %d\n", node, leader)
+	}
+	return nil
+}
+
+func setAddress(nodes, args []string) error {
+	if len(args) != 2 {
+		return errors.New(`id and address are required: paxosctl set-address <id> <address>`)
+	}
+	if err := requireNodes(nodes); err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		if err := post(node, domain.AddressBookEndpoint, domain.Node{ID: id, Address: args[1]}); err != nil {
+			return err
+		}
+		fmt.Printf("%s now addresses node %d at %s\n", node, id, args[1])
+	}
+	return nil
+}
+
+func logLevel(nodes, args []string) error {
+	if len(args) != 1 {
+		return errors.New(`level is required: paxosctl log-level <level>`)
+	}
+	if err := requireNodes(nodes); err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		if err := post(node, domain.LogLevelEndpoint, domain.LogLevel{Level: args[0]}); err != nil {
+			return err
+		}
+		fmt.Printf("log level of %s changed to %s\n", node, strings.ToUpper(args[0]))
+	}
+	return nil
+}
+
+func membership(nodes []string) error {
+	if err := requireNodes(nodes); err != nil {
+		return err
+	}
+
+	var m domain.Membership
+	if err := get(nodes[0], domain.MembershipEndpoint, &m); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "reconfiguration window: %d slots\n\n", m.Window)
+	fmt.Fprintln(w, "EPOCH\tSTATE\tDECIDED\tEFFECTIVE\tID\tROLE\tVOTES\tADDRESS")
+	for _, c := range append([]domain.Configuration{m.Current}, m.Pending...) {
+		state := `pending`
+		if c.Epoch == m.Current.Epoch {
+			state = `current`
+		}
+
+		for _, node := range c.Nodes {
+			fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%d\t%s\t%d\t%s\n", c.Epoch, state, c.Decided, c.Slot, node.ID, node.Role, node.Votes(), node.Address)
+		}
+	}
+
+	return w.Flush()
+}
+
+func addNode(nodes, args []string) error {
+	if len(args) != 3 && len(args) != 4 {
+		return errors.New(`id, role and address are required: paxosctl add-node <id> <role> <address> [weight]`)
+	}
+	if err := requireNodes(nodes); err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return err
+	}
+
+	node := domain.Node{ID: id, Role: args[1], Address: args[2]}
+	if len(args) == 4 {
+		if node.Weight, err = strconv.Atoi(args[3]); err != nil {
+			return err
+		}
+	}
+
+	rc := domain.Reconfiguration{Op: domain.ReconfigAdd, Node: node}
+	if err = post(nodes[0], domain.MembershipEndpoint, rc); err != nil {
+		return err
+	}
+	fmt.Printf("addition of node %d was decided through %s\n", id, nodes[0])
+	return nil
+}
+
+func removeNode(nodes, args []string) error {
+	if len(args) != 1 {
+		return errors.New(`id is required: paxosctl remove-node <id>`)
+	}
+	if err := requireNodes(nodes); err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return err
+	}
+
+	rc := domain.Reconfiguration{Op: domain.ReconfigRemove, Node: domain.Node{ID: id}}
+	if err = post(nodes[0], domain.MembershipEndpoint, rc); err != nil {
+		return err
+	}
+	fmt.Printf("removal of node %d was decided through %s\n", id, nodes[0])
+	return nil
+}
+
+func reload(nodes []string) error {
+	if err := requireNodes(nodes); err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		res, err := reloadConfigs(node)
+		if err != nil {
+			return err
+		}
+
+		if len(res.Changed) == 0 {
+			fmt.Printf("%s has no changed configurations\n", node)
+			continue
+		}
+		fmt.Printf("%s reloaded %s\n", node, strings.Join(res.Changed, `, `))
+	}
+	return nil
+}
+
+func drain(nodes, args []string) error {
+	fs := flag.NewFlagSet(`drain`, flag.ExitOnError)
+	term := fs.Bool(`terminate`, false, `terminates the nodes once they are drained`)
+	timeout := fs.Duration(`timeout`, 30*time.Second, `maximum time to wait for in-flight requests of each node`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireNodes(nodes); err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		if err := post(node, domain.DrainEndpoint, nil); err != nil {
+			return err
+		}
+
+		deadline := time.Now().Add(*timeout)
+		for {
+			st, err := status(node)
+			if err != nil {
+				return err
+			}
+
+			if st.InFlight == 0 {
+				fmt.Printf("%s is drained\n", node)
+				break
+			}
+
+			if time.Now().After(deadline) {
+				return errors.New(fmt.Sprintf(`%s still has %d in-flight requests after %s`, node, st.InFlight, *timeout))
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		if *term {
+			if err := terminate([]string{node}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func terminate(nodes []string) error {
+	if err := requireNodes(nodes); err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		if err := post(node, domain.TermEndpoint, nil); err != nil {
+			return err
+		}
+		fmt.Printf("%s is shutting down\n", node)
+	}
+	return nil
+}
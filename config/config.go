@@ -0,0 +1,245 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-paxos/domain"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	envPrefix = `PAXOS_`
+
+	errRead      = `reading config file failed`
+	errUnmarshal = `unmarshalling config file failed`
+	errInvalid   = `invalid configurations`
+	errLogLevel  = `log_level should be one of the supported levels`
+)
+
+var logLevels = []string{`ERROR`, `WARN`, `INFO`, `DEBUG`, `TRACE`}
+
+// Conf contains all the configurations of a node where the fields tagged with reload can be changed at runtime
+type Conf struct {
+	// timeouts of each operation carried out by the node
+	PrepareTimeout     Duration `yaml:"prepare_timeout" default:"10s" reload:"true"`
+	AcceptTimeout      Duration `yaml:"accept_timeout" default:"10s" reload:"true"`
+	DecisionTimeout    Duration `yaml:"decision_timeout" default:"10s" reload:"true"`
+	ReplicaTimeout     Duration `yaml:"replica_timeout" default:"30s" reload:"true"`
+	ProbeTimeout       Duration `yaml:"probe_timeout" default:"2s" reload:"true"`
+	ShutdownTimeout    Duration `yaml:"shutdown_timeout" default:"30s" reload:"true"`
+	ConsistencyTimeout Duration `yaml:"consistency_timeout" default:"5s" reload:"true"`
+	GapTimeout         Duration `yaml:"gap_timeout" default:"1s" reload:"true"`
+
+	// backoff of a leader before retrying a proposal preempted by another leader, which is randomized within a window
+	// doubled on every preemption up to the maximum, and the number of retries before the proposal is given up
+	PreemptionBackoff    Duration `yaml:"preemption_backoff" default:"20ms" reload:"true"`
+	PreemptionBackoffMax Duration `yaml:"preemption_backoff_max" default:"1s" reload:"true"`
+	PreemptionRetries    int      `yaml:"preemption_retries" default:"3" reload:"true"`
+	// forwards the requests received by a leader to the leader of the highest ballot it has been preempted by
+	ForwardPreempted bool `yaml:"forward_preempted" default:"false" reload:"true"`
+
+	// maximum rate of client requests admitted per second (0 disables rate limiting) and the allowed burst
+	RateLimit float64 `yaml:"rate_limit" default:"0" reload:"true"`
+	RateBurst int     `yaml:"rate_burst" default:"100" reload:"true"`
+
+	// maximum number of entries written to a decision stream at once
+	StreamBatchSize int `yaml:"stream_batch_size" default:"100" reload:"true"`
+
+	// number of slots below the decided watermark of which an acceptor keeps the decided values to answer catch-up
+	// queries, while the state of the older slots is garbage collected
+	DecisionRetention int `yaml:"decision_retention" default:"1000" reload:"true"`
+
+	// number of slots after which a decided reconfiguration of the membership takes effect (same in all the nodes)
+	ReconfigurationWindow int `yaml:"reconfiguration_window" default:"3"`
+
+	// sizes of the prepare (phase-1) and accept (phase-2) quorums, where 0 stands for a majority or for the smallest
+	// size which intersects the other quorum if only that one is set (same in all the nodes)
+	Phase1Quorum int `yaml:"phase1_quorum" default:"0"`
+	Phase2Quorum int `yaml:"phase2_quorum" default:"0"`
+
+	// lets replicas propose values directly to the acceptors in fast rounds, which are decided by fast quorums of the
+	// given size in votes, where 0 stands for the smallest size which is safe with the prepare quorums (same in all
+	// the nodes)
+	FastPaxos  bool `yaml:"fast_paxos" default:"false"`
+	FastQuorum int  `yaml:"fast_quorum" default:"0"`
+
+	// tracing configs
+	TraceFile      string `yaml:"trace_export_file"`
+	TraceCollector string `yaml:"trace_collector_url"`
+
+	// logger configs
+	ColorsEnabled bool   `yaml:"colors_enabled" default:"true"`
+	LogLevel      string `yaml:"log_level" default:"ERROR" reload:"true"`
+	FilePath      bool   `yaml:"file_path" default:"true"`
+
+	Nodes domain.Topology `yaml:"nodes"`
+
+	// path of the file which the configurations were loaded from
+	path string
+}
+
+var (
+	current *Conf
+	lock    = &sync.RWMutex{}
+)
+
+// Get returns the configurations loaded at startup
+func Get() *Conf {
+	lock.RLock()
+	defer lock.RUnlock()
+	return current
+}
+
+// Set replaces the configurations returned by Get
+func Set(c *Conf) {
+	lock.Lock()
+	defer lock.Unlock()
+	current = c
+}
+
+// SetLogLevel replaces the current configurations with a copy of them which has the given log level, so that the log
+// level changed at runtime is reported by the next reload if it differs from the configuration file
+func SetLogLevel(level string) error {
+	if !validLevel(level) {
+		return errors.New(fmt.Sprintf(`%s (%s, found: %s)`, errLogLevel, strings.Join(logLevels, `, `), level))
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	c := *current
+	c.LogLevel = strings.ToUpper(level)
+	current = &c
+	return nil
+}
+
+// Load reads the configurations from the file in given path on top of the defaults declared in struct tags and
+// finally applies the overrides found in environment variables (eg: PAXOS_LOG_LEVEL overrides log_level)
+func Load(path string) (*Conf, error) {
+	c := &Conf{}
+	err := setDefaults(c)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf(`%s (path: %s): %s`, errRead, path, err))
+	}
+
+	err = yaml.Unmarshal(file, c)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf(`%s (path: %s): %s`, errUnmarshal, path, err))
+	}
+
+	err = applyEnv(c)
+	if err != nil {
+		return nil, err
+	}
+
+	c.path = path
+	return c, nil
+}
+
+// Validate checks all the configurations along with the cluster topology for the node with given id and reports
+// every invalid configuration at once
+func (c *Conf) Validate(self int) error {
+	var problems []string
+	for name, d := range map[string]Duration{
+		`prepare_timeout`:        c.PrepareTimeout,
+		`accept_timeout`:         c.AcceptTimeout,
+		`decision_timeout`:       c.DecisionTimeout,
+		`replica_timeout`:        c.ReplicaTimeout,
+		`probe_timeout`:          c.ProbeTimeout,
+		`shutdown_timeout`:       c.ShutdownTimeout,
+		`consistency_timeout`:    c.ConsistencyTimeout,
+		`gap_timeout`:            c.GapTimeout,
+		`preemption_backoff`:     c.PreemptionBackoff,
+		`preemption_backoff_max`: c.PreemptionBackoffMax,
+	} {
+		if d.Duration <= 0 {
+			problems = append(problems, fmt.Sprintf(`%s should be a positive duration (found: %s)`, name, d))
+		}
+	}
+
+	if c.PreemptionBackoffMax.Duration < c.PreemptionBackoff.Duration {
+		problems = append(problems, fmt.Sprintf(`preemption_backoff_max should not be less than preemption_backoff (found: %s < %s)`,
+			c.PreemptionBackoffMax, c.PreemptionBackoff))
+	}
+
+	if c.PreemptionRetries < 0 {
+		problems = append(problems, fmt.Sprintf(`preemption_retries should not be negative (found: %d)`, c.PreemptionRetries))
+	}
+
+	if c.RateLimit < 0 {
+		problems = append(problems, fmt.Sprintf(`rate_limit should not be negative (found: %g)`, c.RateLimit))
+	}
+
+	if c.RateLimit > 0 && c.RateBurst < 1 {
+		problems = append(problems, fmt.Sprintf(`rate_burst should be at least 1 when rate limiting is enabled (found: %d)`, c.RateBurst))
+	}
+
+	if c.StreamBatchSize < 1 {
+		problems = append(problems, fmt.Sprintf(`stream_batch_size should be at least 1 (found: %d)`, c.StreamBatchSize))
+	}
+
+	if c.DecisionRetention < 0 {
+		problems = append(problems, fmt.Sprintf(`decision_retention should not be negative (found: %d)`, c.DecisionRetention))
+	}
+
+	if c.ReconfigurationWindow < 1 {
+		problems = append(problems, fmt.Sprintf(`reconfiguration_window should be at least 1 (found: %d)`, c.ReconfigurationWindow))
+	}
+
+	if !validLevel(c.LogLevel) {
+		problems = append(problems, fmt.Sprintf(`log_level should be one of %s (found: %s)`, strings.Join(logLevels, `, `), c.LogLevel))
+	}
+
+	if c.TraceCollector != `` {
+		if u, err := url.Parse(c.TraceCollector); err != nil || u.Scheme == `` || u.Host == `` {
+			problems = append(problems, fmt.Sprintf(`trace_collector_url should be an absolute url (found: %s)`, c.TraceCollector))
+		}
+	}
+
+	if c.Phase1Quorum < 0 || c.Phase2Quorum < 0 || c.FastQuorum < 0 {
+		problems = append(problems, fmt.Sprintf(`phase1_quorum, phase2_quorum and fast_quorum should not be negative (found: %d, %d, %d)`,
+			c.Phase1Quorum, c.Phase2Quorum, c.FastQuorum))
+	}
+
+	if err := c.Nodes.Validate(self); err != nil {
+		problems = append(problems, err.Error())
+	} else if err = domain.ValidateQuorums(c.Nodes.Votes(), c.Phase1Quorum, c.Phase2Quorum); err != nil {
+		problems = append(problems, err.Error())
+	} else if err = c.ValidateFastQuorum(c.Nodes.Votes()); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if len(problems) > 0 {
+		return errors.New(fmt.Sprintf("%s:\n  - %s", errInvalid, strings.Join(sorted(problems), "\n  - ")))
+	}
+
+	return nil
+}
+
+// ValidateFastQuorum checks the fast quorum against the prepare quorums among the given votes of the acceptors when
+// fast rounds are enabled
+func (c *Conf) ValidateFastQuorum(votes int) error {
+	if !c.FastPaxos {
+		return nil
+	}
+
+	phase1, _, _ := domain.Quorums(votes, c.Phase1Quorum, c.Phase2Quorum)
+	return domain.ValidateFastQuorum(votes, phase1, c.FastQuorum)
+}
+
+func validLevel(level string) bool {
+	for _, l := range logLevels {
+		if l == strings.ToUpper(level) {
+			return true
+		}
+	}
+	return false
+}
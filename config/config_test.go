@@ -0,0 +1,209 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const topology = `
+nodes:
+  - id: 1
+    role: leader
+    address: localhost:7101
+  - id: 2
+    role: leader
+    address: localhost:7102
+  - id: 3
+    role: leader
+    address: localhost:7103
+  - id: 4
+    role: replica
+    address: localhost:7104
+`
+
+// write writes the configurations to the file in given path
+func write(t *testing.T, path, conf string) {
+	t.Helper()
+	if err := ioutil.WriteFile(path, []byte(conf), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func load(t *testing.T, conf string) *Conf {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), `cluster.yaml`)
+	write(t, path, conf)
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestLoad(t *testing.T) {
+	c := load(t, "accept_timeout: 2s\nrate_limit: 50\n"+topology)
+
+	if c.AcceptTimeout.Duration != 2*time.Second {
+		t.Errorf(`expected accept_timeout from the file, found: %s`, c.AcceptTimeout)
+	}
+	if c.PrepareTimeout.Duration != 10*time.Second {
+		t.Errorf(`expected the default prepare_timeout, found: %s`, c.PrepareTimeout)
+	}
+	if c.RateLimit != 50 || c.RateBurst != 100 {
+		t.Errorf(`expected rate_limit from the file and the default rate_burst, found: %g, %d`, c.RateLimit, c.RateBurst)
+	}
+	if c.ReconfigurationWindow != 3 || c.LogLevel != `ERROR` || !c.ColorsEnabled {
+		t.Errorf(`expected the defaults, found: %d, %s, %t`, c.ReconfigurationWindow, c.LogLevel, c.ColorsEnabled)
+	}
+	if len(c.Nodes) != 4 || c.Nodes.Votes() != 3 {
+		t.Errorf(`expected 4 nodes with 3 votes, found: %d nodes with %d votes`, len(c.Nodes), c.Nodes.Votes())
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	os.Setenv(`PAXOS_ACCEPT_TIMEOUT`, `3s`)
+	os.Setenv(`PAXOS_FAST_PAXOS`, `true`)
+	defer os.Unsetenv(`PAXOS_ACCEPT_TIMEOUT`)
+	defer os.Unsetenv(`PAXOS_FAST_PAXOS`)
+
+	c := load(t, "accept_timeout: 2s\n"+topology)
+	if c.AcceptTimeout.Duration != 3*time.Second || !c.FastPaxos {
+		t.Errorf(`expected the environment to override the file, found: %s, %t`, c.AcceptTimeout, c.FastPaxos)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		conf string
+		env  string
+	}{
+		{name: `invalid duration`, conf: "accept_timeout: 2 seconds\n" + topology},
+		{name: `invalid yaml`, conf: "nodes: [\n"},
+		{name: `invalid env`, conf: topology, env: `soon`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.env != `` {
+				os.Setenv(`PAXOS_PREPARE_TIMEOUT`, test.env)
+				defer os.Unsetenv(`PAXOS_PREPARE_TIMEOUT`)
+			}
+
+			path := filepath.Join(t.TempDir(), `cluster.yaml`)
+			write(t, path, test.conf)
+			if _, err := Load(path); err == nil {
+				t.Error(`expected the configurations to be refused`)
+			}
+		})
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), `missing.yaml`)); err == nil {
+		t.Error(`expected a missing file to be refused`)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		conf  string
+		self  int
+		valid bool
+	}{
+		{name: `defaults`, conf: topology, self: 1, valid: true},
+		{name: `flexible quorums`, conf: "phase1_quorum: 3\nphase2_quorum: 1\n" + topology, self: 1, valid: true},
+		{name: `fast quorum`, conf: "fast_paxos: true\nfast_quorum: 3\n" + topology, self: 1, valid: true},
+		{name: `missing self`, conf: topology, self: 5},
+		{name: `zero timeout`, conf: "accept_timeout: 0s\n" + topology, self: 1},
+		{name: `backoff above maximum`, conf: "preemption_backoff: 2s\npreemption_backoff_max: 1s\n" + topology, self: 1},
+		{name: `unknown log level`, conf: "log_level: verbose\n" + topology, self: 1},
+		{name: `relative collector`, conf: "trace_collector_url: collector:4318\n" + topology, self: 1},
+		{name: `zero window`, conf: "reconfiguration_window: 0\n" + topology, self: 1},
+		{name: `negative quorum`, conf: "phase1_quorum: -1\n" + topology, self: 1},
+		{name: `disjoint quorums`, conf: "phase1_quorum: 2\nphase2_quorum: 1\n" + topology, self: 1},
+		{name: `quorum above votes`, conf: "phase2_quorum: 4\n" + topology, self: 1},
+		{name: `unsafe fast quorum`, conf: "fast_paxos: true\nfast_quorum: 2\n" + topology, self: 1},
+		{name: `unsafe fast quorum ignored`, conf: "fast_quorum: 2\n" + topology, self: 1, valid: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := load(t, test.conf).Validate(test.self)
+			if test.valid && err != nil {
+				t.Errorf(`expected valid configurations, found: %s`, err)
+			}
+			if !test.valid && err == nil {
+				t.Error(`expected invalid configurations`)
+			}
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    string
+		level   string // log level changed at runtime before the reload
+		changed []string
+		refused []string
+	}{
+		{name: `unchanged`, conf: topology},
+		{name: `reloadable`, conf: "accept_timeout: 2s\nlog_level: DEBUG\n" + topology, changed: []string{`accept_timeout`, `log_level`}},
+		{name: `not reloadable`, conf: "accept_timeout: 2s\ncolors_enabled: false\n" + topology, refused: []string{`colors_enabled`}},
+		{name: `runtime log level`, conf: topology, level: `debug`, changed: []string{`log_level`}},
+		{name: `topology`, conf: topology + "  - id: 5\n    role: replica\n    address: localhost:7105\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), `cluster.yaml`)
+			write(t, path, topology)
+			c, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			Set(c)
+			if test.level != `` {
+				if err = SetLogLevel(test.level); err != nil {
+					t.Fatal(err)
+				}
+				c = Get()
+			}
+
+			write(t, path, test.conf)
+			changed, refused, err := Reload(1)
+			if (err != nil) != (len(test.refused) > 0) {
+				t.Errorf(`unexpected error: %v`, err)
+			}
+			if !reflect.DeepEqual(changed, test.changed) || !reflect.DeepEqual(refused, test.refused) {
+				t.Errorf(`expected changed: %v, refused: %v, found changed: %v, refused: %v`, test.changed, test.refused, changed, refused)
+			}
+
+			if reloaded := Get() != c; reloaded != (len(test.refused) == 0) {
+				t.Errorf(`expected reloaded: %t, found: %t`, len(test.refused) == 0, reloaded)
+			}
+		})
+	}
+}
+
+func TestReloadInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), `cluster.yaml`)
+	write(t, path, topology)
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Set(c)
+
+	write(t, path, "accept_timeout: 0s\n"+topology)
+	if _, _, err = Reload(1); err == nil {
+		t.Error(`expected invalid configurations to be refused`)
+	}
+	if Get() != c {
+		t.Error(`expected the current configurations to be kept`)
+	}
+}
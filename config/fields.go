@@ -0,0 +1,117 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration is a time.Duration which is configured with a Go duration string (eg: 500ms, 10s)
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	return d.parse(node.Value)
+}
+
+func (d *Duration) parse(val string) error {
+	parsed, err := time.ParseDuration(val)
+	if err != nil {
+		return errors.New(fmt.Sprintf(`invalid duration %q (should be a Go duration string such as 10s)`, val))
+	}
+
+	d.Duration = parsed
+	return nil
+}
+
+var durationType = reflect.TypeOf(Duration{})
+
+// setDefaults assigns the values declared in `default` struct tags
+func setDefaults(c *Conf) error {
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		def, ok := v.Type().Field(i).Tag.Lookup(`default`)
+		if !ok {
+			continue
+		}
+
+		if err := setField(v.Field(i), def); err != nil {
+			return errors.New(fmt.Sprintf(`invalid default for %s: %s`, v.Type().Field(i).Name, err))
+		}
+	}
+
+	return nil
+}
+
+// applyEnv overrides scalar configurations with environment variables named as PAXOS_<upper cased yaml key>
+func applyEnv(c *Conf) error {
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if kind := v.Field(i).Kind(); kind == reflect.Slice || kind == reflect.Map || v.Type().Field(i).PkgPath != `` {
+			continue
+		}
+
+		key := strings.Split(v.Type().Field(i).Tag.Get(`yaml`), `,`)[0]
+		env := envPrefix + strings.ToUpper(key)
+		val, ok := os.LookupEnv(env)
+		if !ok {
+			continue
+		}
+
+		if err := setField(v.Field(i), val); err != nil {
+			return errors.New(fmt.Sprintf(`invalid value for %s: %s`, env, err))
+		}
+	}
+
+	return nil
+}
+
+func setField(field reflect.Value, val string) error {
+	if field.Type() == durationType {
+		var d Duration
+		if err := d.parse(val); err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return errors.New(fmt.Sprintf(`unsupported type %s`, field.Type()))
+	}
+
+	return nil
+}
+
+func sorted(list []string) []string {
+	sort.Strings(list)
+	return list
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const errNotReloadable = `configurations can not be changed without a restart`
+
+// Reload reads the configuration file which the current configurations were loaded from and replaces the current
+// configurations if only the fields tagged as reloadable have changed. The names of the changed fields are returned,
+// or the names of the fields which can not be changed live along with an error.
+func Reload(self int) (changed, refused []string, err error) {
+	old := Get()
+	c, err := Load(old.path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = c.Validate(self)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	oldVal, newVal := reflect.ValueOf(old).Elem(), reflect.ValueOf(c).Elem()
+	for i := 0; i < newVal.NumField(); i++ {
+		field := newVal.Type().Field(i)
+		if field.PkgPath != `` {
+			// unexported fields are not configurations
+			continue
+		}
+
+		// the topology only bootstraps the membership, which is changed by reconfigurations and may no longer match it
+		if field.Name == `Nodes` {
+			continue
+		}
+
+		if reflect.DeepEqual(oldVal.Field(i).Interface(), newVal.Field(i).Interface()) {
+			continue
+		}
+
+		key := strings.Split(field.Tag.Get(`yaml`), `,`)[0]
+		if field.Tag.Get(`reload`) != `true` {
+			refused = append(refused, key)
+			continue
+		}
+		changed = append(changed, key)
+	}
+
+	if len(refused) > 0 {
+		return nil, refused, errors.New(fmt.Sprintf(`%s (%s)`, errNotReloadable, strings.Join(refused, `, `)))
+	}
+
+	Set(c)
+	return changed, nil, nil
+}
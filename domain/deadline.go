@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// TimeoutHeader carries the time left until the deadline of a request as a Go duration string (eg: 2.5s), so that the
+// deadline set by a client flows through the replica and the leader down to the acceptors. A relative duration is
+// used instead of a timestamp so that the clock skew between nodes does not affect it.
+const TimeoutHeader = `Request-Timeout`
+
+// InjectTimeout sets the timeout header of an outgoing request to the time left until the deadline of the context
+func InjectTimeout(ctx context.Context, req *http.Request) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return
+	}
+
+	req.Header.Set(TimeoutHeader, time.Until(deadline).String())
+}
+
+// ExtractTimeout returns the context of an incoming request bounded by the timeout in its header (if there is one).
+// The context is also cancelled when the client goes away.
+func ExtractTimeout(r *http.Request) (context.Context, context.CancelFunc, error) {
+	val := r.Header.Get(TimeoutHeader)
+	if val == `` {
+		ctx, cancel := context.WithCancel(r.Context())
+		return ctx, cancel, nil
+	}
+
+	timeout, err := time.ParseDuration(val)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	return ctx, cancel, nil
+}
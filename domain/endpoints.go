@@ -1,10 +1,37 @@
 package domain
 
+// headers of the consistency tokens
+const (
+	// MinSlotHeader carries the slot which a replica should have applied before serving the request
+	MinSlotHeader = `Min-Slot`
+	// SlotHeader carries the decided slot of a write or the last applied slot of the replica serving a read
+	SlotHeader = `Slot`
+)
+
+// ForwardedHeader carries the id of the leader which has forwarded a request of a replica to another leader
+const ForwardedHeader = `Forwarded-By`
+
 const (
 	RequestReplicaEndpoint = `/replica/request`
 	UpdateReplicaEndpoint  = `/replica/update`
+	LogReplicaEndpoint     = `/replica/log`
+	StreamReplicaEndpoint  = `/replica/stream`
+	PreferLeaderEndpoint   = `/replica/leader`
 	RequestLeaderEndpoint  = `/leader/request`
 	PrepareEndpoint        = `/leader/prepare`
 	AcceptEndpoint         = `/leader/accept`
+	AcceptorStateEndpoint  = `/leader/acceptor-state`
+	DecisionEndpoint       = `/leader/decision`
+	DecisionsEndpoint      = `/leader/decisions`
+	ChosenEndpoint         = `/leader/chosen`
 	TermEndpoint           = `/internal/terminate`
+	MetricsEndpoint        = `/metrics`
+	HealthEndpoint         = `/healthz`
+	ReadyEndpoint          = `/readyz`
+	StatusEndpoint         = `/status`
+	LogLevelEndpoint       = `/admin/log-level`
+	DrainEndpoint          = `/admin/drain`
+	AddressBookEndpoint    = `/admin/address-book`
+	ReloadEndpoint         = `/admin/reload`
+	MembershipEndpoint     = `/admin/membership`
 )
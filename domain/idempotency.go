@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// IdempotencyKeyHeader carries the key which identifies the attempts of the same client request, so that a retried
+// request is decided only once
+const IdempotencyKeyHeader = `Idempotency-Key`
+
+// keyedPrefix marks the log values which carry the idempotency key of the client request along with the value
+const keyedPrefix = `keyed:`
+
+type keyedValue struct {
+	Key string `json:"key"`
+	Val string `json:"val"`
+}
+
+// KeyedValue encodes a client value along with its idempotency key as a value of the replicated log. The value is
+// returned as it is if the key is empty.
+func KeyedValue(key, val string) string {
+	if key == `` {
+		return val
+	}
+
+	data, _ := json.Marshal(keyedValue{Key: key, Val: val})
+	return keyedPrefix + string(data)
+}
+
+// ParseKeyedValue decodes the idempotency key and the client value of a log value where the key is empty if the
+// value was not requested with a key
+func ParseKeyedValue(val string) (key, v string) {
+	if !strings.HasPrefix(val, keyedPrefix) {
+		return ``, val
+	}
+
+	var kv keyedValue
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(val, keyedPrefix)), &kv); err != nil {
+		return ``, val
+	}
+
+	return kv.Key, kv.Val
+}
+
+// IsReserved checks if a client value collides with the encoding of reconfigurations or keyed values
+func IsReserved(val string) bool {
+	return IsReconfiguration(val) || strings.HasPrefix(val, keyedPrefix)
+}
@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	ReconfigAdd    = `add`
+	ReconfigRemove = `remove`
+
+	// reconfigPrefix marks the log values which carry a reconfiguration command instead of a client value
+	reconfigPrefix = `reconfig:`
+
+	errUnknownOp     = `unknown reconfiguration operation`
+	errExistingNode  = `node is already a member of the cluster`
+	errUnknownMember = `node is not a member of the cluster`
+)
+
+// Reconfiguration is a command decided through the replicated log which adds a node to or removes a node from the
+// cluster. Only the id is required to remove a node.
+type Reconfiguration struct {
+	Op   string `json:"op"`
+	Node Node   `json:"node"`
+}
+
+// Value encodes the reconfiguration as a value of the replicated log
+func (rc Reconfiguration) Value() string {
+	data, _ := json.Marshal(rc)
+	return reconfigPrefix + string(data)
+}
+
+// IsReconfiguration checks if the log value carries a reconfiguration command
+func IsReconfiguration(val string) bool {
+	return strings.HasPrefix(val, reconfigPrefix)
+}
+
+// ParseReconfiguration decodes the reconfiguration command carried by a log value
+func ParseReconfiguration(val string) (Reconfiguration, error) {
+	var rc Reconfiguration
+	err := json.Unmarshal([]byte(strings.TrimPrefix(val, reconfigPrefix)), &rc)
+	return rc, err
+}
+
+// Apply returns the topology resulting from the reconfiguration without modifying the current one
+func (t Topology) Apply(rc Reconfiguration) (Topology, error) {
+	var next Topology
+	switch rc.Op {
+	case ReconfigAdd:
+		if _, ok := t.Node(rc.Node.ID); ok {
+			return nil, errors.New(fmt.Sprintf(`%s (id: %d)`, errExistingNode, rc.Node.ID))
+		}
+		next = append(append(next, t...), rc.Node)
+	case ReconfigRemove:
+		if _, ok := t.Node(rc.Node.ID); !ok {
+			return nil, errors.New(fmt.Sprintf(`%s (id: %d)`, errUnknownMember, rc.Node.ID))
+		}
+		for _, node := range t {
+			if node.ID != rc.Node.ID {
+				next = append(next, node)
+			}
+		}
+	default:
+		return nil, errors.New(fmt.Sprintf(`%s (op: %s)`, errUnknownOp, rc.Op))
+	}
+
+	if _, err := next.validate(); err != nil {
+		return nil, err
+	}
+
+	return next, nil
+}
+
+// Configuration is a set of members which decides the slots starting from Slot until the next configuration takes effect
+type Configuration struct {
+	Epoch   int      `json:"epoch"`
+	Decided int      `json:"decided_slot"` // slot of the reconfiguration which created the configuration (-1 for the initial topology)
+	Slot    int      `json:"effective_slot"`
+	Nodes   Topology `json:"nodes"`
+}
+
+type Membership struct {
+	Window  int             `json:"window"`
+	Current Configuration   `json:"current"`
+	Pending []Configuration `json:"pending"`
+}
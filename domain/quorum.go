@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	errQuorumSize         = `quorum size should not be greater than the votes of leaders (acceptors)`
+	errQuorumIntersection = `every phase-1 quorum should intersect every phase-2 quorum (phase-1 + phase-2 > votes)`
+	errFastIntersection   = `any two fast quorums should intersect every phase-1 quorum (2 * fast + phase-1 > 2 * votes)`
+)
+
+// Quorums returns the sizes of the phase-1 (prepare) and phase-2 (accept) quorums in votes among the given votes of
+// the acceptors for the configured sizes, where zero stands for a majority, or for the smallest size which intersects
+// the other quorum if only that one is configured. The quorums are widened to intersect each other if the votes of the
+// acceptors have changed since the sizes were validated, which keeps a phase-1 quorum large enough to find any value
+// which may have been chosen by a phase-2 quorum, and widened reports whether the configured sizes were changed so.
+func Quorums(votes, phase1, phase2 int) (q1, q2 int, widened bool) {
+	majority := votes/2 + 1
+	switch {
+	case phase1 == 0 && phase2 == 0:
+		q1, q2 = majority, majority
+	case phase1 == 0:
+		q1, q2 = votes-phase2+1, phase2
+	case phase2 == 0:
+		q1, q2 = phase1, votes-phase1+1
+	default:
+		q1, q2 = phase1, phase2
+	}
+
+	configured1, configured2 := q1, q2
+	q1, q2 = bound(q1, votes), bound(q2, votes)
+	if q1+q2 <= votes {
+		q1 = votes - q2 + 1
+	}
+
+	return q1, q2, q1 != configured1 || q2 != configured2
+}
+
+// ValidateQuorums checks if the configured quorum sizes fit into the given votes of the acceptors and if every phase-1
+// quorum intersects every phase-2 quorum, which is the case when their sizes add up to more than the votes, since the
+// acceptors of two quorums can not cast more votes than there are without sharing an acceptor
+func ValidateQuorums(votes, phase1, phase2 int) error {
+	for _, size := range []int{phase1, phase2} {
+		if size > votes {
+			return errors.New(fmt.Sprintf(`%s (phase-1: %d, phase-2: %d, votes: %d)`, errQuorumSize, phase1, phase2, votes))
+		}
+	}
+
+	if phase1 == 0 || phase2 == 0 {
+		return nil
+	}
+
+	if phase1+phase2 <= votes {
+		return errors.New(fmt.Sprintf(`%s (phase-1: %d, phase-2: %d, votes: %d)`, errQuorumIntersection, phase1, phase2, votes))
+	}
+
+	return nil
+}
+
+// FastQuorum returns the size of the fast quorums in votes among the given votes of the acceptors for the configured
+// size, where zero stands for the smallest size with which any two fast quorums and the given phase-1 quorum share an
+// acceptor. The fast quorums are widened in the same way if the votes of the acceptors have changed since the size was
+// validated, so that a leader recovering a collided fast round finds at most one value which may have been chosen.
+func FastQuorum(votes, phase1, fast int) int {
+	min := (2*votes-phase1)/2 + 1
+	if fast < min {
+		fast = min
+	}
+
+	return bound(fast, votes)
+}
+
+// ValidateFastQuorum checks if the configured fast quorum size fits into the given votes of the acceptors and if any
+// two fast quorums intersect every phase-1 quorum of the given size
+func ValidateFastQuorum(votes, phase1, fast int) error {
+	if fast > votes {
+		return errors.New(fmt.Sprintf(`%s (fast: %d, votes: %d)`, errQuorumSize, fast, votes))
+	}
+
+	if fast != 0 && 2*fast+phase1 <= 2*votes {
+		return errors.New(fmt.Sprintf(`%s (fast: %d, phase-1: %d, votes: %d)`, errFastIntersection, fast, phase1, votes))
+	}
+
+	return nil
+}
+
+func bound(size, votes int) int {
+	if size < 1 {
+		return 1
+	}
+	if size > votes {
+		return votes
+	}
+	return size
+}
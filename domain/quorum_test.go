@@ -0,0 +1,134 @@
+package domain
+
+import "testing"
+
+func TestQuorums(t *testing.T) {
+	tests := []struct {
+		name           string
+		votes          int
+		phase1, phase2 int
+		q1, q2         int
+		widened        bool
+	}{
+		{name: `majority of odd votes`, votes: 3, q1: 2, q2: 2},
+		{name: `majority of five votes`, votes: 5, q1: 3, q2: 3},
+		{name: `majority of even votes`, votes: 4, q1: 3, q2: 3},
+		{name: `majority of weighted votes`, votes: 7, q1: 4, q2: 4},
+		{name: `single vote`, votes: 1, q1: 1, q2: 1},
+		{name: `phase-2 derived from phase-1`, votes: 5, phase1: 4, q1: 4, q2: 2},
+		{name: `phase-1 derived from phase-2`, votes: 5, phase2: 2, q1: 4, q2: 2},
+		{name: `phase-1 derived from phase-2 of even votes`, votes: 4, phase2: 2, q1: 3, q2: 2},
+		{name: `both configured`, votes: 5, phase1: 3, phase2: 3, q1: 3, q2: 3},
+		{name: `both configured at the boundary`, votes: 5, phase1: 4, phase2: 2, q1: 4, q2: 2},
+		{name: `phase-1 widened to intersect`, votes: 5, phase1: 2, phase2: 2, q1: 4, q2: 2, widened: true},
+		{name: `bounded by shrunk votes`, votes: 3, phase1: 5, q1: 3, q2: 1, widened: true},
+		{name: `phase-1 widened for shrunk votes`, votes: 4, phase1: 2, phase2: 2, q1: 3, q2: 2, widened: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q1, q2, widened := Quorums(test.votes, test.phase1, test.phase2)
+			if q1 != test.q1 || q2 != test.q2 {
+				t.Errorf(`expected quorums (%d, %d), found (%d, %d)`, test.q1, test.q2, q1, q2)
+			}
+			if widened != test.widened {
+				t.Errorf(`expected widened: %t, found: %t`, test.widened, widened)
+			}
+			if q1+q2 <= test.votes {
+				t.Errorf(`quorums (%d, %d) do not intersect among %d votes`, q1, q2, test.votes)
+			}
+		})
+	}
+}
+
+func TestValidateQuorums(t *testing.T) {
+	tests := []struct {
+		name           string
+		votes          int
+		phase1, phase2 int
+		valid          bool
+	}{
+		{name: `majorities`, votes: 5, valid: true},
+		{name: `only phase-1`, votes: 5, phase1: 2, valid: true},
+		{name: `only phase-2`, votes: 5, phase2: 5, valid: true},
+		{name: `intersecting`, votes: 5, phase1: 4, phase2: 2, valid: true},
+		{name: `intersecting even votes`, votes: 4, phase1: 3, phase2: 2, valid: true},
+		{name: `touching`, votes: 5, phase1: 3, phase2: 2},
+		{name: `touching even votes`, votes: 4, phase1: 2, phase2: 2},
+		{name: `phase-1 larger than votes`, votes: 5, phase1: 6},
+		{name: `phase-2 larger than votes`, votes: 5, phase2: 6},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateQuorums(test.votes, test.phase1, test.phase2)
+			if test.valid && err != nil {
+				t.Errorf(`expected valid quorums, found: %s`, err)
+			}
+			if !test.valid && err == nil {
+				t.Error(`expected invalid quorums`)
+			}
+		})
+	}
+}
+
+func TestFastQuorum(t *testing.T) {
+	tests := []struct {
+		name   string
+		votes  int
+		phase1 int
+		fast   int
+		want   int
+	}{
+		{name: `three votes`, votes: 3, phase1: 2, want: 3},
+		{name: `five votes`, votes: 5, phase1: 3, want: 4},
+		{name: `even votes`, votes: 4, phase1: 3, want: 3},
+		{name: `weighted votes`, votes: 7, phase1: 4, want: 6},
+		{name: `large phase-1`, votes: 5, phase1: 5, want: 3},
+		{name: `configured`, votes: 5, phase1: 3, fast: 5, want: 5},
+		{name: `widened for the phase-1`, votes: 5, phase1: 3, fast: 2, want: 4},
+		{name: `bounded by shrunk votes`, votes: 3, phase1: 2, fast: 9, want: 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fast := FastQuorum(test.votes, test.phase1, test.fast)
+			if fast != test.want {
+				t.Errorf(`expected fast quorum %d, found %d`, test.want, fast)
+			}
+			if fast < test.votes && 2*fast+test.phase1 <= 2*test.votes {
+				t.Errorf(`fast quorums of %d do not intersect the phase-1 quorum of %d among %d votes`, fast, test.phase1, test.votes)
+			}
+		})
+	}
+}
+
+func TestValidateFastQuorum(t *testing.T) {
+	tests := []struct {
+		name   string
+		votes  int
+		phase1 int
+		fast   int
+		valid  bool
+	}{
+		{name: `derived`, votes: 5, phase1: 3, valid: true},
+		{name: `smallest safe`, votes: 5, phase1: 3, fast: 4, valid: true},
+		{name: `smallest safe of even votes`, votes: 4, phase1: 3, fast: 3, valid: true},
+		{name: `all votes`, votes: 5, phase1: 3, fast: 5, valid: true},
+		{name: `touching`, votes: 5, phase1: 3, fast: 3},
+		{name: `touching even votes`, votes: 4, phase1: 2, fast: 3},
+		{name: `larger than votes`, votes: 5, phase1: 3, fast: 6},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateFastQuorum(test.votes, test.phase1, test.fast)
+			if test.valid && err != nil {
+				t.Errorf(`expected valid fast quorum, found: %s`, err)
+			}
+			if !test.valid && err == nil {
+				t.Error(`expected invalid fast quorum`)
+			}
+		})
+	}
+}
@@ -1,13 +1,28 @@
 package domain
 
+// Request is a value submitted by a replica, which is decided by the leader for a slot of its choice
 type Request struct {
-	Replica string `json:"replica"`
-	SlotID  int    `json:"slot_id"`
-	Val     string `json:"value"`
+	From int    `json:"from"`
+	Val  string `json:"value"`
+	// slot of a fast round of the value which has collided, which the leader decides before proposing the value
+	Collision *int `json:"collision,omitempty"`
 }
 
+// FastBallot is the proposal id of the fast rounds in which replicas propose values directly to the acceptors. It is
+// lower than the id of any proposal of a leader, so that a leader recovering a collided fast round supersedes it.
+const FastBallot = 1
+
 type Proposal struct {
+	From   int    `json:"from"`
 	ID     int    `json:"id"`
 	SlotID int    `json:"slot_id"`
 	Val    string `json:"val"`
 }
+
+type LeaderPreference struct {
+	Leader int `json:"leader"`
+}
+
+type LogLevel struct {
+	Level string `json:"level"`
+}
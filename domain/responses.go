@@ -1,11 +1,13 @@
 package domain
 
 type Decision struct {
+	From   int    `json:"from"`
 	SlotID int    `json:"slot_id"`
 	Val    string `json:"val"`
 }
 
 type Acceptance struct {
+	From       int `json:"from"`
 	PID        int `json:"pid"`
 	PrvPromise struct {
 		Exists bool   `json:"exists"`
@@ -21,7 +23,51 @@ type Acceptance struct {
 	Accepted bool `json:"accepted"`
 }
 
-type ErrorRes struct {
-	RequestedSlot int `json:"requested_slot"`
-	LastSlot      int `json:"last_slot"`
+// AcceptorState is the state of an acceptor which is collected by a leader recovering from a restart
+type AcceptorState struct {
+	From        int         `json:"from"`
+	LastSlot    int         `json:"last_slot"`
+	DecidedUpTo int         `json:"decided_up_to"`
+	Ballot      int         `json:"ballot"` // highest proposal id promised or accepted by the acceptor
+	Accepted    []SlotState `json:"accepted"`
+	Decided     []Entry     `json:"decided"` // values decided from the requested slot which are still retained
+	// configurations applied by the acceptor, for a leader which skips the slots decided before it recovered
+	Configurations []Configuration `json:"configurations,omitempty"`
+}
+
+// DecisionLog is the decided values retained by an acceptor from the requested slot, which lets a node catch up with
+// the decisions it has missed
+type DecisionLog struct {
+	From        int     `json:"from"`
+	DecidedUpTo int     `json:"decided_up_to"`
+	Floor       int     `json:"floor"` // values of the slots up to this one have been discarded
+	Entries     []Entry `json:"entries"`
+	// configurations applied by the acceptor, for an acceptor which skips the slots it no longer retains the values of
+	Configurations []Configuration `json:"configurations,omitempty"`
+}
+
+type Entry struct {
+	SlotID    int    `json:"slot_id"`
+	Key       string `json:"key,omitempty"`
+	Val       string `json:"val"`
+	Duplicate bool   `json:"duplicate,omitempty"` // the key was decided for a lower slot and the value is not applied
+}
+
+// Value returns the value of the entry as it is stored in the replicated log
+func (e Entry) Value() string {
+	return KeyedValue(e.Key, e.Val)
+}
+
+// Result is the response to a client request which carries the slot in which the value was decided
+type Result struct {
+	SlotID    int    `json:"slot_id"`
+	Val       string `json:"val"`
+	Duplicate bool   `json:"duplicate,omitempty"` // decided by an earlier attempt with the same idempotency key
+	Fast      bool   `json:"fast,omitempty"`      // decided in a fast round without going through a leader
+}
+
+type ReloadResult struct {
+	Changed []string `json:"changed"`
+	Refused []string `json:"refused,omitempty"`
+	Error   string   `json:"error,omitempty"`
 }
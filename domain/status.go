@@ -0,0 +1,48 @@
+package domain
+
+const (
+	RoleLeader  = `leader`
+	RoleReplica = `replica`
+	RoleLearner = `learner` // keeps the log of decisions without voting or forwarding client requests
+)
+
+type Status struct {
+	ID       int            `json:"id"`
+	Role     string         `json:"role"`
+	Hostname string         `json:"hostname"`
+	Draining bool           `json:"draining"`
+	InFlight int64          `json:"in_flight"`
+	Leader   *LeaderStatus  `json:"leader,omitempty"`
+	Replica  *ReplicaStatus `json:"replica,omitempty"`
+	Peers    []Node         `json:"peers"`
+}
+
+type LeaderStatus struct {
+	Ballot      int       `json:"ballot"`
+	LastSlot    int       `json:"last_slot"`
+	DecidedUpTo int       `json:"decided_up_to"` // all slots up to this one are known to be decided by the acceptor
+	Quorums     [2]int    `json:"quorums"`       // sizes of the prepare and accept quorums of the next slot
+	Recovered   bool      `json:"recovered"`     // set once the leader has recovered its state from the acceptors
+	Promised    SlotState `json:"promised"`
+	Accepted    SlotState `json:"accepted"`
+	Leaders     []int     `json:"leaders"`
+	Replicas    []int     `json:"replicas"`
+	// number of decisions waiting to be delivered to each replica and acceptor
+	DecisionQueues map[int]int `json:"decision_queues"`
+	// set if the configured quorum sizes had to be widened to intersect among the votes of the next slot
+	QuorumsWidened bool `json:"quorums_widened"`
+}
+
+type ReplicaStatus struct {
+	LogLength       int   `json:"log_length"`
+	AppliedIndex    int   `json:"applied_index"`
+	PendingLogSize  int   `json:"pending_log_size"`
+	PreferredLeader int   `json:"preferred_leader"`
+	Leaders         []int `json:"leaders"`
+}
+
+type SlotState struct {
+	ID   int    `json:"id"`
+	Slot int    `json:"slot"`
+	Val  string `json:"val"`
+}
@@ -0,0 +1,143 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// MaxNodeID is the upper bound of node ids as proposal ids are built by appending the node id to a timestamp
+const MaxNodeID = 999
+
+const (
+	errNoNodes          = `cluster topology does not contain any nodes`
+	errInvalidID        = `node id in cluster topology is out of range`
+	errDuplicateID      = `duplicate node id in cluster topology`
+	errDuplicateAddress = `duplicate node address in cluster topology`
+	errUnknownRole      = `unknown role in cluster topology`
+	errInvalidAddress   = `invalid node address in cluster topology`
+	errMissingSelf      = `current node is not found in cluster topology`
+	errNoLeaders        = `cluster topology does not contain any leaders`
+	errNoReplicas       = `cluster topology does not contain any replicas`
+	errInvalidWeight    = `vote weight in cluster topology should be positive and only set for leaders`
+)
+
+type Node struct {
+	ID      int    `yaml:"id" json:"id"`
+	Role    string `yaml:"role" json:"role"`
+	Address string `yaml:"address" json:"address"`
+	Weight  int    `yaml:"weight" json:"weight,omitempty"` // votes of a leader in the quorums, which defaults to 1
+}
+
+// Votes returns the number of votes the node casts in the quorums of the acceptors, which is zero unless the node is
+// a leader
+func (n Node) Votes() int {
+	if n.Role != RoleLeader {
+		return 0
+	}
+	if n.Weight == 0 {
+		return 1
+	}
+	return n.Weight
+}
+
+type Topology []Node
+
+// Validate checks the whole topology for duplicate entries, invalid roles, addresses and weights and a missing entry
+// for the current node, while the quorums of the acceptors are checked along with the configured quorum sizes
+func (t Topology) Validate(self int) error {
+	if _, err := t.validate(); err != nil {
+		return err
+	}
+
+	if _, ok := t.Node(self); !ok {
+		return errors.New(fmt.Sprintf(`%s (id: %d)`, errMissingSelf, self))
+	}
+
+	return nil
+}
+
+// validate checks the entries of the topology and returns the number of leaders in it
+func (t Topology) validate() (leaders int, err error) {
+	if len(t) == 0 {
+		return 0, errors.New(errNoNodes)
+	}
+
+	ids, addresses := map[int]bool{}, map[string]bool{}
+	replicas := 0
+	for _, node := range t {
+		if node.ID < 1 || node.ID > MaxNodeID {
+			return 0, errors.New(fmt.Sprintf(`%s (id: %d, range: 1-%d)`, errInvalidID, node.ID, MaxNodeID))
+		}
+
+		if ids[node.ID] {
+			return 0, errors.New(fmt.Sprintf(`%s (id: %d)`, errDuplicateID, node.ID))
+		}
+		ids[node.ID] = true
+
+		if addresses[node.Address] {
+			return 0, errors.New(fmt.Sprintf(`%s (address: %s)`, errDuplicateAddress, node.Address))
+		}
+		addresses[node.Address] = true
+
+		if _, err := Port(node.Address); err != nil {
+			return 0, errors.New(fmt.Sprintf(`%s (id: %d, address: %s)`, errInvalidAddress, node.ID, node.Address))
+		}
+
+		if node.Weight < 0 || (node.Weight != 0 && node.Role != RoleLeader) {
+			return 0, errors.New(fmt.Sprintf(`%s (id: %d, role: %s, weight: %d)`, errInvalidWeight, node.ID, node.Role, node.Weight))
+		}
+
+		switch node.Role {
+		case RoleLeader:
+			leaders++
+		case RoleReplica:
+			replicas++
+		case RoleLearner:
+		default:
+			return 0, errors.New(fmt.Sprintf(`%s (id: %d, role: %s)`, errUnknownRole, node.ID, node.Role))
+		}
+	}
+
+	if leaders == 0 {
+		return 0, errors.New(errNoLeaders)
+	}
+
+	if replicas == 0 {
+		return 0, errors.New(errNoReplicas)
+	}
+
+	return leaders, nil
+}
+
+// Votes returns the total number of votes of the leaders (acceptors)
+func (t Topology) Votes() int {
+	votes := 0
+	for _, node := range t {
+		votes += node.Votes()
+	}
+
+	return votes
+}
+
+// Node returns the entry of the node with given id
+func (t Topology) Node(id int) (Node, bool) {
+	for _, node := range t {
+		if node.ID == id {
+			return node, true
+		}
+	}
+
+	return Node{}, false
+}
+
+// Port extracts the port from an address in the form of <hostname:port>
+func Port(address string) (int, error) {
+	_, p, err := net.SplitHostPort(address)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(p)
+}
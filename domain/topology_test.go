@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"fmt"
+	"testing"
+)
+
+func leader(id, weight int) Node {
+	return Node{ID: id, Role: RoleLeader, Address: address(id), Weight: weight}
+}
+
+func replica(id int) Node {
+	return Node{ID: id, Role: RoleReplica, Address: address(id)}
+}
+
+func address(id int) string {
+	return fmt.Sprintf(`localhost:%d`, 7100+id)
+}
+
+func TestTopologyValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes Topology
+		self  int
+		valid bool
+	}{
+		{name: `odd leaders`, nodes: Topology{leader(1, 0), leader(2, 0), leader(3, 0), replica(4)}, self: 1, valid: true},
+		{name: `single leader`, nodes: Topology{leader(1, 0), replica(2)}, self: 2, valid: true},
+		{name: `weighted even leaders`, nodes: Topology{leader(1, 2), leader(2, 0), leader(3, 0), leader(4, 0), replica(5)}, self: 1, valid: true},
+		{name: `learner`, nodes: Topology{leader(1, 0), replica(2), {ID: 3, Role: RoleLearner, Address: address(3)}}, self: 3, valid: true},
+		{name: `even leaders`, nodes: Topology{leader(1, 0), leader(2, 0), replica(3)}, self: 1, valid: true},
+		{name: `weighted even votes`, nodes: Topology{leader(1, 2), leader(2, 0), leader(3, 0), replica(4)}, self: 1, valid: true},
+		{name: `empty`, nodes: Topology{}, self: 1},
+		{name: `missing self`, nodes: Topology{leader(1, 0), replica(2)}, self: 3},
+		{name: `no leaders`, nodes: Topology{replica(1)}, self: 1},
+		{name: `no replicas`, nodes: Topology{leader(1, 0)}, self: 1},
+		{name: `duplicate id`, nodes: Topology{leader(1, 0), {ID: 1, Role: RoleReplica, Address: address(2)}}, self: 1},
+		{name: `duplicate address`, nodes: Topology{leader(1, 0), {ID: 2, Role: RoleReplica, Address: address(1)}}, self: 1},
+		{name: `id out of range`, nodes: Topology{leader(1, 0), replica(2), {ID: MaxNodeID + 1, Role: RoleReplica, Address: `localhost:9999`}}, self: 1},
+		{name: `unknown role`, nodes: Topology{leader(1, 0), replica(2), {ID: 3, Role: `acceptor`, Address: address(3)}}, self: 1},
+		{name: `invalid address`, nodes: Topology{leader(1, 0), {ID: 2, Role: RoleReplica, Address: `localhost`}}, self: 1},
+		{name: `negative weight`, nodes: Topology{leader(1, -1), replica(2)}, self: 1},
+		{name: `weighted replica`, nodes: Topology{leader(1, 0), {ID: 2, Role: RoleReplica, Address: address(2), Weight: 1}}, self: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.nodes.Validate(test.self)
+			if test.valid && err != nil {
+				t.Errorf(`expected valid topology, found: %s`, err)
+			}
+			if !test.valid && err == nil {
+				t.Error(`expected invalid topology`)
+			}
+		})
+	}
+}
+
+func TestTopologyVotes(t *testing.T) {
+	nodes := Topology{leader(1, 3), leader(2, 0), leader(3, 1), replica(4)}
+	if votes := nodes.Votes(); votes != 5 {
+		t.Errorf(`expected 5 votes, found %d`, votes)
+	}
+}
+
+func TestTopologyApply(t *testing.T) {
+	nodes := Topology{leader(1, 0), leader(2, 0), leader(3, 0), replica(4)}
+	tests := []struct {
+		name  string
+		rc    Reconfiguration
+		ids   []int
+		valid bool
+	}{
+		{name: `add leader`, rc: Reconfiguration{Op: ReconfigAdd, Node: leader(5, 0)}, ids: []int{1, 2, 3, 4, 5}, valid: true},
+		{name: `add replica`, rc: Reconfiguration{Op: ReconfigAdd, Node: replica(5)}, ids: []int{1, 2, 3, 4, 5}, valid: true},
+		{name: `remove leader`, rc: Reconfiguration{Op: ReconfigRemove, Node: Node{ID: 2}}, ids: []int{1, 3, 4}, valid: true},
+		{name: `add existing`, rc: Reconfiguration{Op: ReconfigAdd, Node: replica(4)}},
+		{name: `add duplicate address`, rc: Reconfiguration{Op: ReconfigAdd, Node: Node{ID: 5, Role: RoleReplica, Address: address(4)}}},
+		{name: `add unknown role`, rc: Reconfiguration{Op: ReconfigAdd, Node: Node{ID: 5, Role: `acceptor`, Address: address(5)}}},
+		{name: `remove unknown`, rc: Reconfiguration{Op: ReconfigRemove, Node: Node{ID: 5}}},
+		{name: `remove last replica`, rc: Reconfiguration{Op: ReconfigRemove, Node: Node{ID: 4}}},
+		{name: `unknown op`, rc: Reconfiguration{Op: `replace`, Node: replica(5)}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			next, err := nodes.Apply(test.rc)
+			if !test.valid {
+				if err == nil {
+					t.Error(`expected the reconfiguration to be refused`)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf(`expected the reconfiguration to be applied, found: %s`, err)
+			}
+			if len(next) != len(test.ids) {
+				t.Fatalf(`expected %d nodes, found %d`, len(test.ids), len(next))
+			}
+			for i, id := range test.ids {
+				if next[i].ID != id {
+					t.Errorf(`expected node %d at %d, found %d`, id, i, next[i].ID)
+				}
+			}
+			if len(nodes) != 4 {
+				t.Errorf(`current topology was modified (nodes: %d)`, len(nodes))
+			}
+		})
+	}
+}
+
+func TestTopologyApplyEvenVotes(t *testing.T) {
+	nodes := Topology{leader(1, 0), leader(2, 0), leader(3, 0), replica(4)}
+	next, err := nodes.Apply(Reconfiguration{Op: ReconfigRemove, Node: Node{ID: 3}})
+	if err != nil {
+		t.Fatalf(`expected a leader to be removable before its replacement is added, found: %s`, err)
+	}
+	if votes := next.Votes(); votes != 2 {
+		t.Errorf(`expected 2 votes, found %d`, votes)
+	}
+}
@@ -3,6 +3,7 @@ module github.com/go-paxos
 go 1.16
 
 require (
+	github.com/google/uuid v1.1.1
 	github.com/gorilla/mux v1.8.0
 	github.com/tryfix/log v1.2.1
 	github.com/tryfix/traceable-context v1.0.1
@@ -2,24 +2,46 @@ package logger
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"github.com/go-paxos/config"
 	"github.com/tryfix/log"
 	"runtime"
+	"strings"
+	"sync"
 )
 
-func Init(ctx context.Context) log.Logger {
-	logger := log.Constructor.Log(
-		log.WithColors(config.ColorsEnabled),
-		log.WithLevel(log.Level(config.LogLevel)),
-		log.WithFilePath(config.FilePath),
-	)
+const (
+	// skipFrames includes the frame of the switchable logger so that file paths point to the actual caller
+	skipFrames = 3
+
+	errInvalidLevel   = `invalid log level`
+	errNotInitialized = `logger is not initialized`
+)
+
+// Init creates the logger of the node according to the logger configurations
+func Init(ctx context.Context, conf *config.Conf) log.Logger {
+	root = &switchable{
+		colors:   conf.ColorsEnabled,
+		filePath: conf.FilePath,
+		lock:     &sync.RWMutex{},
+	}
+	root.current = root.newLogger(log.Level(strings.ToUpper(conf.LogLevel)))
 
-	logger.InfoContext(ctx, `logger initialized`)
-	return logger
+	root.InfoContext(ctx, `logger initialized`)
+	return root
+}
+
+func (s *switchable) newLogger(level log.Level) log.Logger {
+	return log.Constructor.Log(
+		log.WithColors(s.colors),
+		log.WithLevel(level),
+		log.WithFilePath(s.filePath),
+		log.WithSkipFrameCount(skipFrames),
+	)
 }
 
+// ErrorWithLine appends the line of the caller to the error, which is still matched by errors.Is and errors.As
 func ErrorWithLine(err error) error {
 	_, file, line, _ := runtime.Caller(1)
-	return errors.New(fmt.Sprintf(`%s - %s:%d`, err.Error(), file, line))
+	return fmt.Errorf(`%w - %s:%d`, err, file, line)
 }
@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/tryfix/log"
+	"strings"
+	"sync"
+)
+
+var levels = map[log.Level]bool{
+	log.ERROR: true,
+	log.WARN:  true,
+	log.INFO:  true,
+	log.DEBUG: true,
+	log.TRACE: true,
+}
+
+// switchable delegates to an underlying logger which can be replaced at runtime to change the log level
+type switchable struct {
+	current  log.Logger
+	colors   bool
+	filePath bool
+	lock     *sync.RWMutex
+}
+
+var root *switchable
+
+// SetLevel replaces the logger returned by Init with a logger of the given level
+func SetLevel(level string) error {
+	lvl := log.Level(strings.ToUpper(level))
+	if !levels[lvl] {
+		return ErrorWithLine(errors.New(fmt.Sprintf(`%s (level: %s)`, errInvalidLevel, level)))
+	}
+
+	if root == nil {
+		return ErrorWithLine(errors.New(errNotInitialized))
+	}
+
+	root.lock.Lock()
+	defer root.lock.Unlock()
+	root.current = root.newLogger(lvl)
+	return nil
+}
+
+func (s *switchable) get() log.Logger {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.current
+}
+
+func (s *switchable) Print(v ...interface{})                 { s.get().Print(v...) }
+func (s *switchable) Printf(format string, v ...interface{}) { s.get().Printf(format, v...) }
+func (s *switchable) Println(v ...interface{})               { s.get().Println(v...) }
+
+func (s *switchable) NewLog(opts ...log.Option) log.Logger { return s.get().NewLog(opts...) }
+func (s *switchable) NewPrefixedLog(opts ...log.Option) log.PrefixedLogger {
+	return s.get().NewPrefixedLog(opts...)
+}
+
+func (s *switchable) Fatal(message interface{}, params ...interface{}) {
+	s.get().Fatal(message, params...)
+}
+func (s *switchable) Error(message interface{}, params ...interface{}) {
+	s.get().Error(message, params...)
+}
+func (s *switchable) Warn(message interface{}, params ...interface{}) {
+	s.get().Warn(message, params...)
+}
+func (s *switchable) Debug(message interface{}, params ...interface{}) {
+	s.get().Debug(message, params...)
+}
+func (s *switchable) Info(message interface{}, params ...interface{}) {
+	s.get().Info(message, params...)
+}
+func (s *switchable) Trace(message interface{}, params ...interface{}) {
+	s.get().Trace(message, params...)
+}
+
+func (s *switchable) FatalContext(ctx context.Context, message interface{}, params ...interface{}) {
+	s.get().FatalContext(ctx, message, params...)
+}
+func (s *switchable) ErrorContext(ctx context.Context, message interface{}, params ...interface{}) {
+	s.get().ErrorContext(ctx, message, params...)
+}
+func (s *switchable) WarnContext(ctx context.Context, message interface{}, params ...interface{}) {
+	s.get().WarnContext(ctx, message, params...)
+}
+func (s *switchable) DebugContext(ctx context.Context, message interface{}, params ...interface{}) {
+	s.get().DebugContext(ctx, message, params...)
+}
+func (s *switchable) InfoContext(ctx context.Context, message interface{}, params ...interface{}) {
+	s.get().InfoContext(ctx, message, params...)
+}
+func (s *switchable) TraceContext(ctx context.Context, message interface{}, params ...interface{}) {
+	s.get().TraceContext(ctx, message, params...)
+}
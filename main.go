@@ -1,22 +1,20 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"github.com/go-paxos/config"
 	"github.com/go-paxos/domain"
 	"github.com/go-paxos/logger"
 	"github.com/go-paxos/roles"
 	"github.com/go-paxos/server"
+	"github.com/go-paxos/tracing"
 	"github.com/google/uuid"
 	traceableContext "github.com/tryfix/traceable-context"
 	"log"
+	"math/rand"
 	"os"
-	"strconv"
-	"strings"
-)
-
-const (
-	typeReplica = `replica`
-	typeLeader  = `leader`
+	"time"
 )
 
 func main() {
@@ -30,50 +28,78 @@ func main() {
                                                                  `)
 	fmt.Println()
 
-	args := os.Args
-	if len(args) != 5 {
-		log.Fatalln(`command should be in the form of ./<program> <replica or leader> <host:port> <list of leaders> <list of replicas> \n
-			eg: ./run leader localhost:2022 localhost:2023,localhost:2024 localhost:2025,localhost:2026`)
+	configPath := flag.String(`config`, envOr(`PAXOS_CONFIG`, `configs.yaml`), `path to the configuration file including the cluster topology (PAXOS_CONFIG)`)
+	nodeID := flag.Int(`id`, 0, `id of the current node in the cluster topology`)
+	dataDir := flag.String(`data-dir`, envOr(`PAXOS_DATA_DIR`, `data`), `data directory of the current node which is bound to its id (PAXOS_DATA_DIR)`)
+	flag.Parse()
+
+	conf, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalln(err)
 	}
 
-	ctx := traceableContext.WithUUID(uuid.New())
-	logger.SetConfigs(ctx)
-	domain.SetConfigs(ctx)
-	logg := logger.Init(ctx)
+	err = conf.Validate(*nodeID)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	config.Set(conf)
 
-	p := port(args[2])
-	leaders, replicas := hosts(args[3]), hosts(args[4])
+	ctx := traceableContext.WithUUID(uuid.New())
+	logg := logger.Init(ctx, conf)
+	logg.InfoContext(ctx, fmt.Sprintf(`configurations loaded from %s`, *configPath))
 
-	var replica *roles.Replica
-	var leader *roles.Leader
-	if args[1] == typeReplica {
-		replica = roles.NewReplica(args[2], leaders, logg)
-	} else if args[1] == typeLeader {
-		leader = roles.NewLeader(args[2], leaders, replicas, logg)
+	self, _ := conf.Nodes.Node(*nodeID)
+	err = tracing.Init(ctx, self.Role+`-`+self.Address, conf.TraceFile, conf.TraceCollector, logg)
+	if err != nil {
+		log.Fatalln(err)
 	}
 
-	server.Init(ctx, p, leader, replica, args[2], logg)
-}
-
-func port(host string) int {
-	list := strings.Split(host, `:`)
-	if len(list) != 2 {
-		log.Fatalln(`hostname should be in the form of <hostname:port>`)
+	p, err := domain.Port(self.Address)
+	if err != nil {
+		log.Fatalln(err)
 	}
 
-	p, err := strconv.Atoi(list[1])
+	err = roles.PersistIdentity(*dataDir, self.ID)
 	if err != nil {
 		log.Fatalln(err)
 	}
 
-	return p
-}
+	book := roles.NewAddressBook(conf.Nodes)
+	err = roles.CheckIdentity(ctx, self, book)
+	if err != nil {
+		log.Fatalln(`refusing to join the cluster:`, err)
+	}
+
+	// backoffs of dueling leaders should not follow the same sequence
+	rand.Seed(time.Now().UnixNano() + int64(self.ID))
 
-func hosts(arg string) []string {
-	list := strings.Split(arg, `,`)
-	if len(list) == 0 {
-		log.Fatalln(`leader/replica list is empty`)
+	members := roles.NewMembership(conf.Nodes, conf.ReconfigurationWindow, book)
+	var replica *roles.Replica
+	var leader *roles.Leader
+	switch self.Role {
+	case domain.RoleReplica:
+		replica = roles.NewReplica(self.ID, members, book, logg)
+	case domain.RoleLearner:
+		replica = roles.NewLearner(self.ID, members, book, logg)
+	case domain.RoleLeader:
+		leader = roles.NewLeader(self.ID, members, book, logg)
+		go leader.Recover(ctx)
+	}
+
+	if replica != nil {
+		go func() {
+			if err := replica.CatchUp(ctx); err != nil {
+				logg.WarnContext(ctx, err)
+			}
+		}()
 	}
 
-	return list
+	server.Init(ctx, p, leader, replica, self, book, logg)
+}
+
+func envOr(key, def string) string {
+	if val, ok := os.LookupEnv(key); ok {
+		return val
+	}
+	return def
 }
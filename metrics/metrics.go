@@ -0,0 +1,277 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the latency buckets (in seconds) used by histograms unless stated otherwise
+var DefaultBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+const (
+	typeCounter   = `counter`
+	typeGauge     = `gauge`
+	typeHistogram = `histogram`
+)
+
+// collector is implemented by every metric type which can be exposed in prometheus text format
+type collector interface {
+	write(w io.Writer)
+}
+
+// Registry holds all metrics exposed by the node
+type Registry struct {
+	collectors []collector
+	lock       *sync.Mutex
+}
+
+var defaultRegistry = &Registry{lock: &sync.Mutex{}}
+
+func (r *Registry) register(c collector) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// Write encodes all the registered metrics in prometheus text exposition format
+func (r *Registry) Write(w io.Writer) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, c := range r.collectors {
+		c.write(w)
+	}
+}
+
+// Write encodes metrics of the default registry
+func Write(w io.Writer) {
+	defaultRegistry.Write(w)
+}
+
+type desc struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+}
+
+func (d desc) header(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, d.help, d.name, d.typ)
+}
+
+// key joins label values in the order of label names so that it can be used as a map key, and reports false if the
+// number of values does not match the label names in which case the update is dropped rather than failing the caller
+func (d desc) key(values []string) (string, bool) {
+	if len(values) != len(d.labels) {
+		return ``, false
+	}
+	return strings.Join(values, "\xff"), true
+}
+
+func (d desc) labelPairs(key string, extra ...string) string {
+	var pairs []string
+	if len(d.labels) > 0 {
+		for i, val := range strings.Split(key, "\xff") {
+			pairs = append(pairs, fmt.Sprintf(`%s="%s"`, d.labels[i], escape(val)))
+		}
+	}
+
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, extra[i], extra[i+1]))
+	}
+
+	if len(pairs) == 0 {
+		return ``
+	}
+	return `{` + strings.Join(pairs, `,`) + `}`
+}
+
+func escape(val string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(val)
+}
+
+func formatFloat(val float64) string {
+	if math.IsInf(val, 1) {
+		return `+Inf`
+	}
+	return strconv.FormatFloat(val, 'g', -1, 64)
+}
+
+func sortedKeys(m map[string]*float64) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+/* Counters and gauges */
+
+// valueVec is a set of float values partitioned by label values, shared by counters and gauges
+type valueVec struct {
+	desc
+	values map[string]*float64
+	lock   *sync.Mutex
+}
+
+func newValueVec(d desc) *valueVec {
+	v := &valueVec{desc: d, values: map[string]*float64{}, lock: &sync.Mutex{}}
+	if len(d.labels) == 0 {
+		v.values[``] = new(float64)
+	}
+	defaultRegistry.register(v)
+	return v
+}
+
+func (v *valueVec) update(values []string, fn func(val float64) float64) {
+	k, ok := v.key(values)
+	if !ok {
+		return
+	}
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	val, exists := v.values[k]
+	if !exists {
+		val = new(float64)
+		v.values[k] = val
+	}
+	*val = fn(*val)
+}
+
+func (v *valueVec) write(w io.Writer) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.header(w)
+	for _, k := range sortedKeys(v.values) {
+		fmt.Fprintf(w, "%s%s %s\n", v.name, v.labelPairs(k), formatFloat(*v.values[k]))
+	}
+}
+
+// CounterVec is a monotonically increasing value partitioned by labels
+type CounterVec struct {
+	vec *valueVec
+}
+
+// NewCounterVec registers a counter with the given label names in the default registry, which is then incremented with
+// label values in the same order
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{vec: newValueVec(desc{name: name, help: help, typ: typeCounter, labels: labels})}
+}
+
+// Inc increments the counter with given label values by one
+func (c *CounterVec) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+// Add adds a non-negative delta to the counter with given label values
+func (c *CounterVec) Add(delta float64, labels ...string) {
+	if delta < 0 {
+		return
+	}
+	c.vec.update(labels, func(val float64) float64 { return val + delta })
+}
+
+// GaugeVec is a value which can go up and down, partitioned by labels
+type GaugeVec struct {
+	vec *valueVec
+}
+
+// NewGaugeVec registers a gauge with the given label names in the default registry, which is then set with label values
+// in the same order
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{vec: newValueVec(desc{name: name, help: help, typ: typeGauge, labels: labels})}
+}
+
+// Set sets the gauge with given label values
+func (g *GaugeVec) Set(val float64, labels ...string) {
+	g.vec.update(labels, func(float64) float64 { return val })
+}
+
+// Add adds the delta (which can be negative) to the gauge with given label values
+func (g *GaugeVec) Add(delta float64, labels ...string) {
+	g.vec.update(labels, func(val float64) float64 { return val + delta })
+}
+
+/* Histograms */
+
+type histogramVal struct {
+	counts []uint64 // cumulative counts are calculated when writing
+	count  uint64
+	sum    float64
+}
+
+// HistogramVec samples observations into configured buckets, partitioned by labels
+type HistogramVec struct {
+	desc
+	buckets []float64
+	values  map[string]*histogramVal
+	lock    *sync.Mutex
+}
+
+// NewHistogramVec registers a histogram with the given upper bounds of its buckets in increasing order and label names
+// in the default registry, which then observes values with label values in the same order
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{
+		desc:    desc{name: name, help: help, typ: typeHistogram, labels: labels},
+		buckets: buckets,
+		values:  map[string]*histogramVal{},
+		lock:    &sync.Mutex{},
+	}
+	defaultRegistry.register(h)
+	return h
+}
+
+// Observe adds a single observation to the histogram with given label values
+func (h *HistogramVec) Observe(val float64, labels ...string) {
+	k, ok := h.key(labels)
+	if !ok {
+		return
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	hv, exists := h.values[k]
+	if !exists {
+		hv = &histogramVal{counts: make([]uint64, len(h.buckets))}
+		h.values[k] = hv
+	}
+
+	for i, upper := range h.buckets {
+		if val <= upper {
+			hv.counts[i]++
+			break
+		}
+	}
+	hv.count++
+	hv.sum += val
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.header(w)
+
+	var keys []string
+	for k := range h.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		hv := h.values[k]
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += hv.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(k, `le`, formatFloat(upper)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(k, `le`, `+Inf`), hv.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labelPairs(k), formatFloat(hv.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labelPairs(k), hv.count)
+	}
+}
@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// exposition returns the lines of the default registry which belong to the metric with given name
+func exposition(name string) string {
+	var buf bytes.Buffer
+	Write(&buf)
+
+	var lines []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.HasPrefix(line, name) || strings.HasPrefix(line, `# HELP `+name+` `) || strings.HasPrefix(line, `# TYPE `+name+` `) {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestWrite(t *testing.T) {
+	counter := NewCounterVec(`test_requests_total`, `Requests by path`, `path`)
+	counter.Inc(`/a`)
+	counter.Add(2, `/a`)
+	counter.Add(-1, `/a`)
+	counter.Inc("quote\" backslash\\ newline\n")
+
+	gauge := NewGaugeVec(`test_queue_depth`, `Queued items`)
+	gauge.Set(5)
+	gauge.Add(-2)
+
+	histogram := NewHistogramVec(`test_duration_seconds`, `Durations by status`, []float64{.1, 1}, `status`)
+	histogram.Observe(.05, `200`)
+	histogram.Observe(.5, `200`)
+	histogram.Observe(.5, `200`)
+	histogram.Observe(3, `200`)
+
+	tests := []struct {
+		name     string
+		metric   string
+		expected string
+	}{
+		{name: `counter with escaped labels`, metric: `test_requests_total`, expected: `# HELP test_requests_total Requests by path
+# TYPE test_requests_total counter
+test_requests_total{path="/a"} 3
+test_requests_total{path="quote\" backslash\\ newline\n"} 1`},
+		{name: `gauge without labels`, metric: `test_queue_depth`, expected: `# HELP test_queue_depth Queued items
+# TYPE test_queue_depth gauge
+test_queue_depth 3`},
+		{name: `histogram with cumulative buckets`, metric: `test_duration_seconds`, expected: `# HELP test_duration_seconds Durations by status
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{status="200",le="0.1"} 1
+test_duration_seconds_bucket{status="200",le="1"} 3
+test_duration_seconds_bucket{status="200",le="+Inf"} 4
+test_duration_seconds_sum{status="200"} 4.05
+test_duration_seconds_count{status="200"} 4`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if found := exposition(test.metric); found != test.expected {
+				t.Errorf("expected:\n%s\nfound:\n%s", test.expected, found)
+			}
+		})
+	}
+}
+
+func TestLabelMismatch(t *testing.T) {
+	counter := NewCounterVec(`test_mismatch_total`, `Updates with a wrong number of label values`, `kind`)
+	counter.Inc()
+	counter.Inc(`a`, `b`)
+	histogram := NewHistogramVec(`test_mismatch_seconds`, `Observations with a wrong number of label values`, []float64{1})
+	histogram.Observe(1, `a`)
+
+	expected := `# HELP test_mismatch_total Updates with a wrong number of label values
+# TYPE test_mismatch_total counter`
+	if found := exposition(`test_mismatch_total`); found != expected {
+		t.Errorf("expected the updates to be dropped:\n%s\nfound:\n%s", expected, found)
+	}
+	if found := exposition(`test_mismatch_seconds`); strings.Contains(found, `_count`) {
+		t.Errorf("expected the observation to be dropped, found:\n%s", found)
+	}
+}
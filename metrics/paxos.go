@@ -0,0 +1,65 @@
+package metrics
+
+const (
+	LabelAccepted  = `accepted`
+	LabelRejected  = `rejected`
+	LabelPreempted = `preempted`
+	LabelChosen    = `chosen`
+	LabelNotChosen = `not_chosen`
+	LabelLeader    = `leader`
+	LabelReplica   = `replica`
+	LabelLearner   = `learner`
+	LabelForwarded = `forwarded`
+	LabelFailed    = `failed`
+	LabelCollided  = `collided`
+)
+
+var (
+	PhaseDuration = NewHistogramVec(`paxos_leader_phase_duration_seconds`,
+		`Latency of prepare and accept rounds carried out by the proposer`, DefaultBuckets, `phase`)
+
+	Promises = NewCounterVec(`paxos_leader_promises_total`,
+		`Promises received by the proposer partitioned by whether they were accepted, rejected or preempted by a higher proposal`, `result`)
+
+	Proposals = NewCounterVec(`paxos_leader_proposals_total`,
+		`Proposals initiated by the proposer partitioned by whether the value was chosen or not`, `result`)
+
+	Preemptions = NewCounterVec(`paxos_leader_preemptions_total`,
+		`Prepare rounds of the proposer preempted by a proposal with a higher id for the same slot`)
+
+	RecoveredSlots = NewCounterVec(`paxos_leader_recovered_slots_total`,
+		`Slots proposed again by a recovering leader partitioned by whether the value was chosen or not`, `result`)
+
+	Collisions = NewCounterVec(`paxos_leader_collisions_total`,
+		`Collided fast rounds recovered by the proposer partitioned by whether the value of the requester was chosen or not`, `result`)
+
+	ForwardedRequests = NewCounterVec(`paxos_leader_forwarded_requests_total`,
+		`Requests forwarded to the leader with the highest ballot partitioned by whether the leader responded or not`, `result`)
+
+	BroadcastFailures = NewCounterVec(`paxos_leader_broadcast_failures_total`,
+		`Attempts of delivering a decision to a replica or to an acceptor which failed`)
+
+	DecisionQueueDepth = NewGaugeVec(`paxos_leader_decision_queue_depth`,
+		`Number of decisions waiting to be delivered to a replica or to an acceptor`, `node`)
+
+	AcceptorSlots = NewGaugeVec(`paxos_leader_acceptor_slots`,
+		`Number of promised, accepted and decided slots retained by the acceptor`)
+
+	SlotIndex = NewGaugeVec(`paxos_slot_index`,
+		`Last slot known to be decided by the node`, `role`)
+
+	PendingLogSize = NewGaugeVec(`paxos_replica_pending_log_size`,
+		`Number of decisions received for future slots which are not yet applied to the replica log`)
+
+	FastRounds = NewCounterVec(`paxos_replica_fast_rounds_total`,
+		`Fast rounds started by the replica partitioned by whether the value was chosen or the round collided`, `result`)
+
+	DuplicateKeys = NewCounterVec(`paxos_replica_duplicate_keys_total`,
+		`Values decided again with an idempotency key which was decided for a lower slot and not applied by the replica`)
+
+	StreamSubscribers = NewGaugeVec(`paxos_replica_stream_subscribers`,
+		`Number of consumers subscribed to the stream of decided entries`)
+
+	ClientRequestDuration = NewHistogramVec(`paxos_client_request_duration_seconds`,
+		`Latency of client requests served by the replica partitioned by response status`, DefaultBuckets, `status`)
+)
@@ -0,0 +1,251 @@
+package roles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-paxos/config"
+	"github.com/go-paxos/domain"
+	"github.com/go-paxos/logger"
+	"github.com/go-paxos/tracing"
+	"sort"
+	"strconv"
+	"sync/atomic"
+)
+
+/* Event loop functions */
+
+// isDecided checks if the slot is known to be decided by the acceptor. Should only be called by the event loop.
+func (l *Leader) isDecided(slot int) bool {
+	if slot <= l.state.decidedUpTo {
+		return true
+	}
+	_, ok := l.state.decisions[slot]
+	return ok
+}
+
+// learn records the value decided for the slot and drops the proposals of the slot
+func (l *Leader) learn(slot int, val string) {
+	s := &l.state
+	if slot <= s.floor {
+		return
+	}
+	if _, ok := s.decisions[slot]; ok {
+		return
+	}
+
+	s.decisions[slot] = val
+	delete(s.promises, slot)
+	delete(s.accepts, slot)
+	l.advance(slot)
+	l.watermark()
+}
+
+// skip marks all slots up to the given one as decided without knowing their values
+func (l *Leader) skip(upTo int, configs []domain.Configuration) {
+	s := &l.state
+	if upTo <= s.decidedUpTo {
+		return
+	}
+
+	l.members.Adopt(configs, upTo)
+	l.warnWidened()
+	for _, entry := range l.decisionEntries(s.decidedUpTo+1, -1) {
+		if entry.SlotID <= upTo {
+			l.apply(entry.SlotID, entry.Val)
+		}
+	}
+
+	for slot := range s.promises {
+		if slot <= upTo {
+			delete(s.promises, slot)
+		}
+	}
+	for slot := range s.accepts {
+		if slot <= upTo {
+			delete(s.accepts, slot)
+		}
+	}
+	s.decidedUpTo = upTo
+	l.advance(upTo)
+	l.watermark()
+}
+
+// watermark moves the decided-up-to watermark over the slots decided in order
+func (l *Leader) watermark() {
+	s := &l.state
+	for {
+		val, ok := s.decisions[s.decidedUpTo+1]
+		if !ok {
+			break
+		}
+		s.decidedUpTo++
+		l.apply(s.decidedUpTo, val)
+	}
+	l.release()
+
+	floor := s.decidedUpTo - config.Get().DecisionRetention
+	if floor <= s.floor {
+		return
+	}
+
+	if floor-s.floor > len(s.decisions) {
+		for slot := range s.decisions {
+			if slot <= floor {
+				delete(s.decisions, slot)
+			}
+		}
+	} else {
+		for slot := s.floor + 1; slot <= floor; slot++ {
+			delete(s.decisions, slot)
+		}
+	}
+	s.floor = floor
+}
+
+// warnWidened warns if the configured quorum sizes do not intersect among the votes of the latest configuration
+func (l *Leader) warnWidened() {
+	votes := l.members.Latest().Nodes.Votes()
+	if phase1, phase2, widened := quorums(votes); widened {
+		conf := config.Get()
+		l.logger.Warn(fmt.Sprintf(`%s (configured: %d/%d, effective: %d/%d, votes: %d)`, errQuorumsWidened,
+			conf.Phase1Quorum, conf.Phase2Quorum, phase1, phase2, votes))
+	}
+}
+
+// apply applies the reconfiguration decided at the given slot once all slots below it are decided
+func (l *Leader) apply(slot int, val string) {
+	rc, applied, err := l.members.Apply(slot, val)
+	if err != nil {
+		l.logger.Error(err)
+		return
+	}
+
+	if !applied {
+		return
+	}
+	l.logger.Info(fmt.Sprintf(`membership reconfigured (op: %s, node: %d, decided slot: %d, epoch: %d)`,
+		rc.Op, rc.Node.ID, slot, l.members.Latest().Epoch))
+	l.warnWidened()
+
+	if rc.Op == domain.ReconfigAdd && rc.Node.Role == domain.RoleLeader && rc.Node.ID != l.id {
+		dec := domain.Decision{From: l.id, SlotID: slot, Val: val}
+		l.outbox(rc.Node.ID, domain.DecisionEndpoint).push(queued{ctx: context.Background(), dec: dec})
+	}
+}
+
+// release notifies the proposers waiting for the slots which are decided by now
+func (l *Leader) release() {
+	s := &l.state
+	waiting := s.waiters[:0]
+	for _, w := range s.waiters {
+		if w.upTo <= s.decidedUpTo {
+			w.reply <- struct{}{}
+			continue
+		}
+		waiting = append(waiting, w)
+	}
+	s.waiters = waiting
+}
+
+// decisionEntries returns the retained values decided from the given slot, up to max entries unless it is negative
+func (l *Leader) decisionEntries(from, limit int) []domain.Entry {
+	var slots []int
+	for slot := range l.state.decisions {
+		if slot >= from {
+			slots = append(slots, slot)
+		}
+	}
+	sort.Ints(slots)
+	if limit >= 0 && len(slots) > limit {
+		slots = slots[:limit]
+	}
+
+	entries := make([]domain.Entry, 0, len(slots))
+	for _, slot := range slots {
+		entries = append(entries, domain.Entry{SlotID: slot, Val: l.state.decisions[slot]})
+	}
+	return entries
+}
+
+// decisionLog returns a batch of the retained values decided from the given slot
+func (l *Leader) decisionLog(from int) domain.DecisionLog {
+	return domain.DecisionLog{
+		From:        l.id,
+		DecidedUpTo: l.state.decidedUpTo,
+		Floor:       l.state.floor,
+		Entries:     l.decisionEntries(from, decisionLogLimit),
+		// lets an acceptor adopt the configurations decided in the slots it skips
+		Configurations: l.members.Configurations(),
+	}
+}
+
+/* Acceptor functions */
+
+// HandleDecision records a decision notice sent by a leader
+func (l *Leader) HandleDecision(ctx context.Context, dec domain.Decision) {
+	l.learned([]domain.Entry{{SlotID: dec.SlotID, Val: dec.Val}}, -1, nil)
+
+	st := l.snapshot()
+	if st.lastSlot-st.decidedUpTo > l.members.window {
+		go l.catchUp(tracing.Detach(ctx))
+	}
+}
+
+// DecisionLog returns the values decided from the given slot which are still retained by the acceptor
+func (l *Leader) DecisionLog(from int) domain.DecisionLog {
+	reply := make(chan domain.DecisionLog, 1)
+	l.events <- decisionLogMsg{from: from, reply: reply}
+	return <-reply
+}
+
+// catchUp requests the decisions missed by the acceptor from the other acceptors until one of them responds
+func (l *Leader) catchUp(ctx context.Context) {
+	if !atomic.CompareAndSwapInt32(&l.catchingUp, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&l.catchingUp, 0)
+
+	ctx, span := tracing.Start(ctx, `acceptor.catch_up`)
+	defer span.End()
+
+	for _, node := range l.members.Latest().Nodes {
+		if node.Role != domain.RoleLeader || node.ID == l.id {
+			continue
+		}
+
+		err := l.catchUpWith(ctx, node.ID)
+		if err == nil {
+			return
+		}
+		l.logger.DebugContext(ctx, err)
+	}
+
+	err := logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (decided up to: %d)`, errAcceptorCatchUp, l.snapshot().decidedUpTo)))
+	span.SetError(err)
+	l.logger.WarnContext(ctx, err)
+}
+
+// catchUpWith learns the retained decisions of a single acceptor in batches
+func (l *Leader) catchUpWith(ctx context.Context, acceptor int) error {
+	from := l.snapshot().decidedUpTo + 1
+	for {
+		var dl domain.DecisionLog
+		err := l.get(ctx, acceptor, domain.DecisionsEndpoint+`?from=`+strconv.Itoa(from), &dl)
+		if err != nil {
+			return logger.ErrorWithLine(err)
+		}
+
+		upTo := dl.DecidedUpTo
+		if len(dl.Entries) == decisionLogLimit && dl.Entries[len(dl.Entries)-1].SlotID < upTo {
+			upTo = dl.Entries[len(dl.Entries)-1].SlotID
+		}
+		l.learned(dl.Entries, upTo, dl.Configurations)
+
+		if upTo == dl.DecidedUpTo {
+			l.logger.DebugContext(ctx, fmt.Sprintf(`acceptor caught up with acceptor %d (decided up to: %d)`, acceptor, upTo))
+			return nil
+		}
+		from = upTo + 1
+	}
+}
@@ -1,15 +1,60 @@
 package roles
 
+import "time"
+
+const (
+	// backoff of retrying a decision which could not be delivered to a replica
+	decisionBackoffMin = 100 * time.Millisecond
+	decisionBackoffMax = 10 * time.Second
+
+	// backoff of retrying the recovery of a started leader until a quorum of acceptors is reachable
+	recoveryBackoffMin = 100 * time.Millisecond
+	recoveryBackoffMax = 5 * time.Second
+
+	// maximum number of decided values returned to a node catching up in a single response
+	decisionLogLimit = 1000
+)
+
 const (
-	idLimit     = 1000
 	typePrepare = `prepare`
 	typeAccept  = `accept`
+	typeFast    = `fast`
 
-	errFutureSlot      = `received future slot`
 	errBroadcast       = `sending decision to replicas failed`
+	errDrain           = `decision queues are not drained within the shutdown timeout`
 	errRequestAcceptor = `received non-2xx code for acceptor response`
+	errRequestNode     = `received non-2xx code for node response`
 	errInvalidProposal = `acceptor received an older proposal`
+	errSlotDecided     = `proposal is for a slot which has already been decided`
+	errFastDisabled    = `fast rounds are not enabled in the cluster`
+	errUndecidedGap    = `configuration of the slot is not known due to undecided slots below it`
 
 	errNoLeader        = `no leader found in the replica`
 	errInvalidDecision = `received a decision for an invalid slot`
+	errUnknownLeader   = `leader is not known to the replica`
+	errRequestLeader   = `received non-2xx code for leader response`
+	errAnnounce        = `announcing the value chosen in a fast round to the leaders failed`
+
+	errNoQuorum      = `quorum of acceptors is not reachable`
+	errNotCaughtUp   = `replica has not applied all received decisions`
+	errNoReadyLeader = `none of the leaders is ready`
+	errProbe         = `received non-2xx code for health probe`
+	errClosed        = `node is shutting down`
+
+	errInvalidAddress   = `invalid node address`
+	errUnknownNode      = `node is not found in the address book`
+	errAddressInUse     = `address is already used by another node`
+	errIdentityMismatch = `data directory belongs to a different node`
+	errDuplicateID      = `another node is already running with the same id`
+
+	errInvalidReconfig = `decided reconfiguration is ignored`
+	errNotMember       = `node is not a member of the configuration of the slot`
+	errReservedValue   = `value is reserved for reconfiguration commands`
+	errCatchUp         = `catching up with replica failed`
+	errDiscarded       = `decision of the slot has been discarded by the leader`
+	errLearner         = `learners do not accept client requests`
+	errNotApplied      = `replica has not applied the requested slot in time`
+	errRecovering      = `leader has not recovered its state from the acceptors`
+	errAcceptorCatchUp = `catching up with the decisions of the other acceptors failed`
+	errQuorumsWidened  = `configured quorum sizes do not intersect and are widened`
 )
@@ -0,0 +1,249 @@
+package roles
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-paxos/config"
+	"github.com/go-paxos/domain"
+	"github.com/go-paxos/logger"
+	"github.com/go-paxos/metrics"
+	"github.com/go-paxos/tracing"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// queued is a decision waiting to be delivered along with the trace of the proposal which chose it
+type queued struct {
+	ctx context.Context
+	dec domain.Decision
+}
+
+// outbox delivers the decisions chosen by the leader to a single replica, learner or acceptor in slot order
+type outbox struct {
+	replica  int
+	endpoint string // endpoint of the node which receives the decisions depending on its role
+	queue    []queued
+	signal   chan struct{}
+	done     chan struct{} // closed once the delivery loop has stopped
+	lock     *sync.Mutex
+}
+
+func newOutbox(replica int, endpoint string) *outbox {
+	return &outbox{replica: replica, endpoint: endpoint, signal: make(chan struct{}, 1), done: make(chan struct{}), lock: &sync.Mutex{}}
+}
+
+// push inserts the decision in slot order and wakes up the delivery loop
+func (o *outbox) push(item queued) {
+	o.lock.Lock()
+	i := sort.Search(len(o.queue), func(i int) bool { return o.queue[i].dec.SlotID > item.dec.SlotID })
+	o.queue = append(o.queue, queued{})
+	copy(o.queue[i+1:], o.queue[i:])
+	o.queue[i] = item
+	o.updateMetrics()
+	o.lock.Unlock()
+
+	select {
+	case o.signal <- struct{}{}:
+	default:
+	}
+}
+
+// head returns the decision with the lowest slot in the queue
+func (o *outbox) head() (queued, bool) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if len(o.queue) == 0 {
+		return queued{}, false
+	}
+
+	return o.queue[0], true
+}
+
+// pop removes the given decision from the queue once it is delivered
+func (o *outbox) pop(dec domain.Decision) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	for i, item := range o.queue {
+		if item.dec == dec {
+			o.queue = append(o.queue[:i], o.queue[i+1:]...)
+			break
+		}
+	}
+	o.updateMetrics()
+}
+
+// clear drops all the queued decisions
+func (o *outbox) clear() {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.queue = nil
+	o.updateMetrics()
+}
+
+func (o *outbox) depth() int {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return len(o.queue)
+}
+
+// updateMetrics should be called while holding the lock
+func (o *outbox) updateMetrics() {
+	metrics.DecisionQueueDepth.Set(float64(len(o.queue)), strconv.Itoa(o.replica))
+}
+
+// disseminate queues the decision for the replicas and learners of the slot excluding the requester
+func (l *Leader) disseminate(ctx context.Context, dec domain.Decision, requester int) {
+	item := queued{ctx: tracing.Detach(ctx), dec: dec}
+	for _, replica := range append(l.members.IDs(dec.SlotID, domain.RoleReplica, requester), l.members.IDs(dec.SlotID, domain.RoleLearner, requester)...) {
+		l.outbox(replica, domain.UpdateReplicaEndpoint).push(item)
+	}
+
+	for _, acceptor := range l.members.IDs(dec.SlotID, domain.RoleLeader, l.id) {
+		l.outbox(acceptor, domain.DecisionEndpoint).push(item)
+	}
+}
+
+// outbox returns the outbox of the given node and starts its delivery loop if it does not exist yet
+func (l *Leader) outbox(replica int, endpoint string) *outbox {
+	l.outboxLock.Lock()
+	defer l.outboxLock.Unlock()
+	o, ok := l.outboxes[replica]
+	if !ok {
+		o = newOutbox(replica, endpoint)
+		l.outboxes[replica] = o
+		go l.deliver(o)
+	}
+
+	return o
+}
+
+// deliver sends the queued decisions of an outbox one at a time until the queue is drained after the leader is closed
+func (l *Leader) deliver(o *outbox) {
+	defer close(o.done)
+	backoff := decisionBackoffMin
+	for {
+		item, ok := o.head()
+		if !ok {
+			select {
+			case <-o.signal:
+				continue
+			case <-l.done:
+				return
+			}
+		}
+
+		if _, ok = l.members.Latest().Nodes.Node(o.replica); !ok && !l.members.IsMember(item.dec.SlotID, o.replica) {
+			l.logger.InfoContext(item.ctx, fmt.Sprintf(`dropped %d decisions queued for removed replica %d`, o.depth(), o.replica))
+			l.outboxLock.Lock()
+			delete(l.outboxes, o.replica)
+			l.outboxLock.Unlock()
+			o.clear()
+			return
+		}
+
+		retry, err := l.sendDecision(item.ctx, o.replica, o.endpoint, item.dec)
+		if err != nil {
+			metrics.BroadcastFailures.Inc()
+			l.logger.WarnContext(item.ctx, err)
+		}
+
+		if err == nil || !retry {
+			o.pop(item.dec)
+			backoff = decisionBackoffMin
+			continue
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-l.done:
+			// a closed leader does not wait for an unreachable replica to come back
+			l.logger.WarnContext(item.ctx, fmt.Sprintf(`dropped %d decisions queued for unreachable replica %d`, o.depth(), o.replica))
+			return
+		}
+		if backoff *= 2; backoff > decisionBackoffMax {
+			backoff = decisionBackoffMax
+		}
+	}
+}
+
+// drain waits for the delivery loops to send the decisions which are queued by the time the leader is closed
+func (l *Leader) drain(ctx context.Context) {
+	l.outboxLock.Lock()
+	outboxes := make([]*outbox, 0, len(l.outboxes))
+	for _, o := range l.outboxes {
+		outboxes = append(outboxes, o)
+	}
+	l.outboxLock.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, config.Get().ShutdownTimeout.Duration)
+	defer cancel()
+	for _, o := range outboxes {
+		select {
+		case <-o.done:
+		case <-ctx.Done():
+			l.logger.WarnContext(ctx, fmt.Sprintf(`%s (queued decisions: %v)`, errDrain, l.DecisionQueues()))
+			return
+		}
+	}
+}
+
+// sendDecision delivers a decision to the given endpoint of a replica or an acceptor
+func (l *Leader) sendDecision(ctx context.Context, replica int, endpoint string, dec domain.Decision) (retry bool, err error) {
+	ctx, span := tracing.Start(ctx, `leader.decision`)
+	span.SetAttribute(`slot`, dec.SlotID)
+	span.SetAttribute(`replica`, replica)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
+	data, err := json.Marshal(dec)
+	if err != nil {
+		return false, logger.ErrorWithLine(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, config.Get().DecisionTimeout.Duration)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, `http://`+l.book.Address(replica)+endpoint, bytes.NewBuffer(data))
+	if err != nil {
+		return false, logger.ErrorWithLine(err)
+	}
+	tracing.Inject(ctx, req)
+	domain.InjectTimeout(ctx, req)
+
+	res, err := l.client.Do(req)
+	if err != nil {
+		return true, logger.ErrorWithLine(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		err = logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (replica: %d, slot: %d, status: %d)`, errBroadcast, replica, dec.SlotID, res.StatusCode)))
+		return res.StatusCode >= http.StatusInternalServerError, err
+	}
+
+	return false, nil
+}
+
+// DecisionQueues returns the number of decisions waiting to be delivered to each replica and acceptor
+func (l *Leader) DecisionQueues() map[int]int {
+	l.outboxLock.Lock()
+	outboxes := make([]*outbox, 0, len(l.outboxes))
+	for _, o := range l.outboxes {
+		outboxes = append(outboxes, o)
+	}
+	l.outboxLock.Unlock()
+
+	depths := map[int]int{}
+	for _, o := range outboxes {
+		depths[o.replica] = o.depth()
+	}
+
+	return depths
+}
@@ -0,0 +1,94 @@
+package roles
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-paxos/domain"
+	"github.com/go-paxos/logger"
+	"github.com/go-paxos/tracing"
+	"io/ioutil"
+	"net/http"
+)
+
+// requestFunc sends a proposal to a single acceptor
+type requestFunc func(ctx context.Context, acceptor int) (domain.Acceptance, error)
+
+// logFunc logs the failures of the acceptors at the level chosen by the proposer
+type logFunc func(ctx context.Context, message interface{}, params ...interface{})
+
+// fanOut sends a proposal to every acceptor of the given votes and tallies the responses until the round is done
+func fanOut(ctx context.Context, typ string, slot int, votes map[int]int, request requestFunc, log logFunc) (*round, error) {
+	rnd := newRound(typ, votes)
+	// the requests still in flight are cancelled once the outcome of the round is known
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// buffered for every acceptor so that the requests completing after the round never block
+	resChan := make(chan response, len(votes))
+	for acceptor := range votes {
+		go func(acceptor int) {
+			res, err := request(ctx, acceptor)
+			resChan <- response{acceptor: acceptor, res: res, err: err}
+		}(acceptor)
+	}
+
+	for !rnd.done() {
+		select {
+		case res := <-resChan:
+			if res.err != nil && res.err != errStale {
+				log(ctx, res.err)
+			}
+			rnd.add(res)
+		case <-ctx.Done():
+			return nil, logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (phase: %s, slot: %d, responded votes: %d, quorum: %d): %s`,
+				errNoQuorum, typ, slot, rnd.accepted+rnd.rejected, rnd.quorum, ctx.Err())))
+		}
+	}
+
+	return rnd, nil
+}
+
+// requestAcceptor sends the encoded proposal to a single acceptor over http in the given phase
+func requestAcceptor(ctx context.Context, client *http.Client, book *AddressBook, typ string, acceptor int, data []byte) (domain.Acceptance, error) {
+	endpoint := domain.AcceptEndpoint
+	if typ == typePrepare {
+		endpoint = domain.PrepareEndpoint
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, `http://`+book.Address(acceptor)+endpoint, bytes.NewBuffer(data))
+	if err != nil {
+		return domain.Acceptance{}, errors.New(fmt.Sprintf(`%s for acceptor: %d`, err.Error(), acceptor))
+	}
+	tracing.Inject(ctx, req)
+	domain.InjectTimeout(ctx, req)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return domain.Acceptance{}, errors.New(fmt.Sprintf(`%s for acceptor: %d`, err.Error(), acceptor))
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusConflict {
+		return domain.Acceptance{}, errStale
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return domain.Acceptance{}, errors.New(fmt.Sprintf(`%s (type: %s, status: %d) for acceptor: %d`, errRequestAcceptor, typ, res.StatusCode, acceptor))
+	}
+
+	resData, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return domain.Acceptance{}, errors.New(fmt.Sprintf(`%s for acceptor: %d`, err.Error(), acceptor))
+	}
+
+	var response domain.Acceptance
+	err = json.Unmarshal(resData, &response)
+	if err != nil {
+		return domain.Acceptance{}, errors.New(fmt.Sprintf(`%s for acceptor: %d`, err.Error(), acceptor))
+	}
+
+	return response, nil
+}
@@ -0,0 +1,122 @@
+package roles
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-paxos/config"
+	"github.com/go-paxos/domain"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// maxHang bounds the time a hanging acceptor waits for the request to be cancelled
+const maxHang = 5 * time.Second
+
+// acceptor returns an acceptor which handles every request with the given behaviour, and reports the requests which
+// are cancelled by the proposer to the given channel
+func acceptor(behaviour string, cancelled chan<- string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch behaviour {
+		case `accept`:
+			var prop domain.Proposal
+			_ = json.NewDecoder(r.Body).Decode(&prop)
+			_ = json.NewEncoder(w).Encode(domain.Acceptance{PID: prop.ID, Accepted: true})
+		case `reject`:
+			w.WriteHeader(http.StatusConflict)
+		case `error`:
+			w.WriteHeader(http.StatusInternalServerError)
+		case `hang`:
+			// the closed connection is only noticed once the request body is read
+			_, _ = io.Copy(ioutil.Discard, r.Body)
+			select {
+			case <-r.Context().Done():
+				cancelled <- behaviour
+			case <-time.After(maxHang):
+				w.WriteHeader(http.StatusGatewayTimeout)
+			}
+		}
+	}))
+}
+
+func TestFanOut(t *testing.T) {
+	tests := []struct {
+		name      string
+		acceptors []string
+		timeout   time.Duration
+		failed    bool
+		succeeded bool
+		reachable bool
+		cancelled int
+	}{
+		{name: `quorum accepted`, acceptors: []string{`accept`, `accept`, `hang`}, timeout: maxHang, succeeded: true, reachable: true, cancelled: 1},
+		{name: `quorum rejected`, acceptors: []string{`reject`, `hang`, `reject`}, timeout: maxHang, reachable: true, cancelled: 1},
+		{name: `quorum failed`, acceptors: []string{`error`, `error`, `hang`}, timeout: maxHang, cancelled: 1},
+		{name: `quorum despite a failure`, acceptors: []string{`accept`, `error`, `hang`, `accept`, `accept`}, timeout: maxHang, succeeded: true, reachable: true, cancelled: 1},
+		{name: `all hang`, acceptors: []string{`hang`, `hang`, `hang`}, timeout: 100 * time.Millisecond, failed: true, cancelled: 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config.Set(&config.Conf{})
+			cancelled := make(chan string, len(test.acceptors))
+			book := NewAddressBook(nil)
+			votes := map[int]int{}
+			for i, behaviour := range test.acceptors {
+				srv := acceptor(behaviour, cancelled)
+				defer srv.Close()
+				book.Add(domain.Node{ID: i + 1, Address: strings.TrimPrefix(srv.URL, `http://`)})
+				votes[i+1] = 1
+			}
+
+			var inflight int32
+			request := func(ctx context.Context, acceptor int) (domain.Acceptance, error) {
+				atomic.AddInt32(&inflight, 1)
+				defer atomic.AddInt32(&inflight, -1)
+				return requestAcceptor(ctx, http.DefaultClient, book, typeAccept, acceptor, []byte(`{"id":20}`))
+			}
+			logf := func(ctx context.Context, message interface{}, params ...interface{}) {}
+
+			ctx, cancel := context.WithTimeout(context.Background(), test.timeout)
+			defer cancel()
+			start := time.Now()
+			rnd, err := fanOut(ctx, typeAccept, 1, votes, request, logf)
+			if elapsed := time.Since(start); elapsed >= maxHang {
+				t.Fatalf(`expected an early return, took: %s`, elapsed)
+			}
+
+			if (err != nil) != test.failed {
+				t.Fatalf(`expected failed: %t, found error: %v`, test.failed, err)
+			}
+			if err == nil {
+				if succeeded := rnd.succeeded(); succeeded != test.succeeded {
+					t.Errorf(`expected succeeded: %t, found: %t`, test.succeeded, succeeded)
+				}
+				if reachable := rnd.reachable(); reachable != test.reachable {
+					t.Errorf(`expected reachable: %t, found: %t`, test.reachable, reachable)
+				}
+			}
+
+			// the hanging acceptors see their requests cancelled and the request goroutines exit
+			for i := 0; i < test.cancelled; i++ {
+				select {
+				case <-cancelled:
+				case <-time.After(time.Second):
+					t.Fatalf(`expected %d cancelled requests, found: %d`, test.cancelled, i)
+				}
+			}
+			deadline := time.Now().Add(time.Second)
+			for atomic.LoadInt32(&inflight) != 0 {
+				if time.Now().After(deadline) {
+					t.Fatalf(`expected no requests in flight, found: %d`, atomic.LoadInt32(&inflight))
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package roles
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-paxos/config"
+	"github.com/go-paxos/domain"
+	"github.com/go-paxos/logger"
+	"github.com/go-paxos/metrics"
+	"github.com/go-paxos/tracing"
+	"net/http"
+)
+
+/* Proposer functions */
+
+// recoverCollision decides the collided slot of a fast round and reports whether the value of the request was chosen
+func (l *Leader) recoverCollision(ctx context.Context, slot int, req domain.Request) (dec domain.Decision, ok bool, err error) {
+	if err = l.awaitConfiguration(ctx, slot); err == errGap {
+		return domain.Decision{}, false, nil
+	} else if err != nil {
+		return domain.Decision{}, false, logger.ErrorWithLine(err)
+	}
+
+	if !l.members.IsMember(slot, l.id) {
+		return domain.Decision{}, false, nil
+	}
+
+	// the slot may have been decided by another leader, or announced by a replica of which the fast round has succeeded
+	dl := l.DecisionLog(slot)
+	if len(dl.Entries) > 0 && dl.Entries[0].SlotID == slot {
+		if dl.Entries[0].Val != req.Val {
+			metrics.Collisions.Inc(metrics.LabelNotChosen)
+			return domain.Decision{}, false, nil
+		}
+		metrics.Collisions.Inc(metrics.LabelChosen)
+		return domain.Decision{From: l.id, SlotID: slot, Val: req.Val}, true, nil
+	}
+	if slot <= dl.DecidedUpTo {
+		metrics.Collisions.Inc(metrics.LabelNotChosen)
+		return domain.Decision{}, false, nil
+	}
+
+	prop, promises, err := l.prepareRound(ctx, slot, req)
+	if err != nil {
+		return domain.Decision{}, false, logger.ErrorWithLine(err)
+	}
+
+	if !promises.recoverable() {
+		l.logger.DebugContext(ctx, fmt.Sprintf(`recovery of collided slot %d was preempted by %d`, slot, promises.ballot))
+		metrics.Collisions.Inc(metrics.LabelNotChosen)
+		return domain.Decision{}, false, nil
+	}
+
+	requester := req.From
+	if adopted := promises.adopted(); adopted.id != 0 && adopted.val != req.Val {
+		prop.Val = adopted.val
+		requester = 0
+	}
+
+	dec, ok, err = l.decide(ctx, prop, requester)
+	if err != nil {
+		return domain.Decision{}, false, logger.ErrorWithLine(err)
+	}
+
+	if !ok || requester == 0 {
+		metrics.Collisions.Inc(metrics.LabelNotChosen)
+		return domain.Decision{}, false, nil
+	}
+	metrics.Collisions.Inc(metrics.LabelChosen)
+	return dec, true, nil
+}
+
+/* Acceptor functions */
+
+// HandleChosen records the value chosen by a replica in a fast round and delivers it to the other nodes
+func (l *Leader) HandleChosen(ctx context.Context, dec domain.Decision) {
+	l.learned([]domain.Entry{{SlotID: dec.SlotID, Val: dec.Val}}, -1, nil)
+	l.disseminate(ctx, dec, dec.From)
+}
+
+/* Replica functions */
+
+// requestFast proposes the value in the fast round of a free slot and falls back to a leader if the round collides
+func (r *Replica) requestFast(ctx context.Context, val string) (dec domain.Decision, fast bool, err error) {
+	if r.isClosed() {
+		return domain.Decision{}, false, logger.ErrorWithLine(errors.New(errClosed))
+	}
+
+	slot, ok := r.reserveSlot()
+	if !ok {
+		dec, err = r.request(ctx, val)
+		return dec, false, err
+	}
+	defer r.releaseSlot(slot)
+
+	chosen, err := r.fastRound(ctx, slot, val)
+	if err != nil {
+		r.logger.DebugContext(ctx, err)
+	}
+
+	if !chosen {
+		metrics.FastRounds.Inc(metrics.LabelCollided)
+		dec, err = r.send(ctx, domain.Request{From: r.id, Val: val, Collision: &slot})
+		if err != nil {
+			return domain.Decision{}, false, logger.ErrorWithLine(err)
+		}
+
+		if err = r.Update(ctx, dec); err != nil {
+			return domain.Decision{}, false, logger.ErrorWithLine(err)
+		}
+		return dec, false, nil
+	}
+
+	metrics.FastRounds.Inc(metrics.LabelChosen)
+	dec = domain.Decision{From: r.id, SlotID: slot, Val: val}
+	if err = r.Update(ctx, dec); err != nil {
+		return domain.Decision{}, false, logger.ErrorWithLine(err)
+	}
+
+	go r.announce(tracing.Detach(ctx), dec)
+	return dec, true, nil
+}
+
+// reserveSlot reserves the lowest free slot of the replica within the reconfiguration window for a fast round
+func (r *Replica) reserveSlot() (int, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for slot := len(r.log); slot < len(r.log)+r.members.window; slot++ {
+		if _, ok := r.pendingLog[slot]; ok || r.reserved[slot] {
+			continue
+		}
+		r.reserved[slot] = true
+		return slot, true
+	}
+
+	return 0, false
+}
+
+// releaseSlot releases a slot reserved by a fast round once the round is completed
+func (r *Replica) releaseSlot(slot int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.reserved, slot)
+}
+
+// fastRound sends the value in the fast round of the slot and reports whether a fast quorum has accepted it
+func (r *Replica) fastRound(ctx context.Context, slot int, val string) (chosen bool, err error) {
+	ctx, span := tracing.Start(ctx, `replica.fast_round`)
+	span.SetAttribute(`slot`, slot)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
+	prop := domain.Proposal{From: r.id, ID: domain.FastBallot, SlotID: slot, Val: val}
+	data, err := json.Marshal(prop)
+	if err != nil {
+		return false, logger.ErrorWithLine(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, config.Get().AcceptTimeout.Duration)
+	defer cancel()
+
+	request := func(ctx context.Context, acceptor int) (domain.Acceptance, error) {
+		return requestAcceptor(ctx, r.client, r.book, typeFast, acceptor, data)
+	}
+	rnd, err := fanOut(ctx, typeFast, slot, r.members.Votes(slot), request, r.logger.DebugContext)
+	if err != nil {
+		return false, logger.ErrorWithLine(err)
+	}
+
+	span.SetAttribute(`accepted`, rnd.accepted)
+	span.SetAttribute(`rejected`, rnd.rejected)
+	return rnd.succeeded(), nil
+}
+
+// announce notifies the leaders of a value chosen in a fast round in the order of preference until one records it
+func (r *Replica) announce(ctx context.Context, dec domain.Decision) {
+	ctx, span := tracing.Start(ctx, `replica.announce`)
+	span.SetAttribute(`slot`, dec.SlotID)
+	defer span.End()
+
+	data, err := json.Marshal(dec)
+	if err != nil {
+		r.logger.ErrorContext(ctx, logger.ErrorWithLine(err))
+		return
+	}
+
+	r.lock.Lock()
+	leaders := r.leaders
+	r.lock.Unlock()
+
+	for _, leader := range leaders {
+		if !r.members.IsMember(dec.SlotID, leader) {
+			continue
+		}
+
+		err = r.announceTo(ctx, leader, data)
+		if err == nil {
+			return
+		}
+		r.logger.DebugContext(ctx, err)
+	}
+
+	err = logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (slot: %d)`, errAnnounce, dec.SlotID)))
+	span.SetError(err)
+	r.logger.WarnContext(ctx, err)
+}
+
+// announceTo sends the value chosen in a fast round to a single leader
+func (r *Replica) announceTo(ctx context.Context, leader int, data []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, config.Get().DecisionTimeout.Duration)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, `http://`+r.book.Address(leader)+domain.ChosenEndpoint, bytes.NewBuffer(data))
+	if err != nil {
+		return logger.ErrorWithLine(err)
+	}
+	tracing.Inject(ctx, req)
+	domain.InjectTimeout(ctx, req)
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return logger.ErrorWithLine(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (leader: %d, status: %d)`, errRequestLeader, leader, res.StatusCode)))
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package roles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-paxos/config"
+	"github.com/go-paxos/domain"
+	"github.com/go-paxos/logger"
+	"net/http"
+)
+
+var healthClient = &http.Client{}
+
+// Status returns a snapshot of the proposer and acceptor state of the leader
+func (l *Leader) Status() domain.LeaderStatus {
+	st := l.snapshot()
+	phase1, phase2, widened := quorums(l.members.At(st.lastSlot + 1).Nodes.Votes())
+	return domain.LeaderStatus{
+		Ballot:         st.promised.id,
+		LastSlot:       st.lastSlot,
+		DecidedUpTo:    st.decidedUpTo,
+		Quorums:        [2]int{phase1, phase2},
+		QuorumsWidened: widened,
+		Recovered:      l.Recovered(),
+		Promised:       domain.SlotState{ID: st.promised.id, Slot: st.promised.slot, Val: st.promised.val},
+		Accepted:       domain.SlotState{ID: st.accepted.id, Slot: st.accepted.slot, Val: st.accepted.val},
+		Leaders:        l.members.IDs(st.lastSlot+1, domain.RoleLeader, l.id),
+		Replicas:       l.members.IDs(st.lastSlot+1, domain.RoleReplica, l.id),
+		DecisionQueues: l.DecisionQueues(),
+	}
+}
+
+// Ready checks if the leader can reach a prepare and an accept quorum of acceptors of its next slot
+func (l *Leader) Ready(ctx context.Context) error {
+	if !l.Recovered() {
+		return logger.ErrorWithLine(errors.New(errRecovering))
+	}
+
+	next := l.lastSlot() + 1
+
+	if !l.members.IsMember(next, l.id) {
+		return logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (id: %d, slot: %d)`, errNotMember, l.id, next)))
+	}
+
+	votes := l.members.Votes(next)
+	reachable, total := votes[l.id], 0
+	for acceptor, v := range votes {
+		total += v
+		if acceptor == l.id {
+			continue
+		}
+		if err := probe(ctx, l.book.Address(acceptor), domain.HealthEndpoint); err != nil {
+			l.logger.DebugContext(ctx, err)
+			continue
+		}
+		reachable += v
+	}
+
+	phase1, phase2, _ := quorums(total)
+	if reachable < phase1 || reachable < phase2 {
+		return logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (reachable votes: %d, votes: %d, quorums: %d/%d)`,
+			errNoQuorum, reachable, total, phase1, phase2)))
+	}
+
+	return nil
+}
+
+// Status returns a snapshot of the log state of the replica
+func (r *Replica) Status() domain.ReplicaStatus {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var preferred int
+	if len(r.leaders) > 0 {
+		preferred = r.leaders[0]
+	}
+
+	return domain.ReplicaStatus{
+		LogLength:       len(r.log),
+		AppliedIndex:    len(r.log) - 1,
+		PendingLogSize:  len(r.pendingLog),
+		PreferredLeader: preferred,
+		Leaders:         r.leaders,
+	}
+}
+
+// Ready checks if the replica has applied the decisions it has received and if any of the leaders is ready
+func (r *Replica) Ready(ctx context.Context) error {
+	r.lock.Lock()
+	pending, leaders := len(r.pendingLog), r.leaders
+	r.lock.Unlock()
+
+	if pending != 0 {
+		return logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (pending decisions: %d)`, errNotCaughtUp, pending)))
+	}
+
+	if r.IsLearner() {
+		return nil
+	}
+
+	for _, leader := range leaders {
+		if err := probe(ctx, r.book.Address(leader), domain.ReadyEndpoint); err != nil {
+			r.logger.DebugContext(ctx, err)
+			continue
+		}
+		return nil
+	}
+
+	return logger.ErrorWithLine(errors.New(errNoReadyLeader))
+}
+
+// probe returns an error if the endpoint of the node does not respond with a success code
+func probe(ctx context.Context, host, endpoint string) error {
+	ctx, cancel := context.WithTimeout(ctx, config.Get().ProbeTimeout.Duration)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, `http://`+host+endpoint, nil)
+	if err != nil {
+		return logger.ErrorWithLine(err)
+	}
+	domain.InjectTimeout(ctx, req)
+
+	res, err := healthClient.Do(req)
+	if err != nil {
+		return logger.ErrorWithLine(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (host: %s, endpoint: %s, status: %d)`, errProbe, host, endpoint, res.StatusCode)))
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package roles
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-paxos/config"
+	"github.com/go-paxos/domain"
+	"github.com/go-paxos/logger"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const identityFile = `node.id`
+
+// AddressBook maps node ids to their current addresses so that peers are addressed by their identities
+type AddressBook struct {
+	nodes map[int]domain.Node
+	lock  *sync.RWMutex
+}
+
+func NewAddressBook(topology domain.Topology) *AddressBook {
+	b := &AddressBook{nodes: map[int]domain.Node{}, lock: &sync.RWMutex{}}
+	for _, node := range topology {
+		b.nodes[node.ID] = node
+	}
+
+	return b
+}
+
+// Address returns the current address of the node with given id
+func (b *AddressBook) Address(id int) string {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	return b.nodes[id].Address
+}
+
+// Set updates the address of a known node
+func (b *AddressBook) Set(id int, address string) error {
+	if _, err := domain.Port(address); err != nil {
+		return logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (id: %d, address: %s)`, errInvalidAddress, id, address)))
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	node, ok := b.nodes[id]
+	if !ok {
+		return logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (id: %d)`, errUnknownNode, id)))
+	}
+
+	for _, n := range b.nodes {
+		if n.ID != id && n.Address == address {
+			return logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (address: %s, id: %d)`, errAddressInUse, address, n.ID)))
+		}
+	}
+
+	node.Address = address
+	b.nodes[id] = node
+	return nil
+}
+
+// Add registers a node which has joined the cluster, keeping the address of the node if it is already known
+func (b *AddressBook) Add(node domain.Node) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if _, ok := b.nodes[node.ID]; !ok {
+		b.nodes[node.ID] = node
+	}
+}
+
+// Nodes returns all the nodes known to the address book ordered by id
+func (b *AddressBook) Nodes() []domain.Node {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	var nodes []domain.Node
+	for _, node := range b.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+
+	return nodes
+}
+
+// PersistIdentity stores the id of the node in the given data directory and refuses a directory of another node
+func PersistIdentity(dir string, id int) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return logger.ErrorWithLine(err)
+	}
+
+	path := filepath.Join(dir, identityFile)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ioutil.WriteFile(path, []byte(strconv.Itoa(id)), 0644)
+	}
+
+	if err != nil {
+		return logger.ErrorWithLine(err)
+	}
+
+	persisted, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return logger.ErrorWithLine(err)
+	}
+
+	if persisted != id {
+		return logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (data dir: %s, persisted id: %d, requested id: %d)`, errIdentityMismatch, dir, persisted, id)))
+	}
+
+	return nil
+}
+
+// CheckIdentity returns an error if any of the reachable peers is already running with the id of the node
+func CheckIdentity(ctx context.Context, self domain.Node, book *AddressBook) error {
+	for _, node := range book.Nodes() {
+		if node.ID == self.ID {
+			continue
+		}
+
+		probeCtx, cancel := context.WithTimeout(ctx, config.Get().ProbeTimeout.Duration)
+		req, err := http.NewRequestWithContext(probeCtx, http.MethodGet, `http://`+node.Address+domain.StatusEndpoint, nil)
+		if err != nil {
+			cancel()
+			return logger.ErrorWithLine(err)
+		}
+
+		res, err := healthClient.Do(req)
+		if err != nil {
+			cancel()
+			// peers which are not up yet can not conflict with the current node
+			continue
+		}
+
+		var status domain.Status
+		err = json.NewDecoder(res.Body).Decode(&status)
+		res.Body.Close()
+		cancel()
+		if err != nil {
+			continue
+		}
+
+		if status.ID == self.ID {
+			return logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (id: %d, address: %s)`, errDuplicateID, self.ID, node.Address)))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package roles
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPersistIdentity(t *testing.T) {
+	tests := []struct {
+		name    string
+		ids     []int // ids of the node in the order of its restarts
+		refused bool
+	}{
+		{name: `first start`, ids: []int{1}},
+		{name: `restart with the same id`, ids: []int{1, 1}},
+		{name: `restart with a different id`, ids: []int{1, 2}, refused: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), `data`)
+			var err error
+			for _, id := range test.ids {
+				if err = PersistIdentity(dir, id); err != nil {
+					break
+				}
+			}
+
+			if refused := err != nil; refused != test.refused {
+				t.Errorf(`expected refused: %t, found error: %v`, test.refused, err)
+			}
+		})
+	}
+}
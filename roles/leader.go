@@ -1,32 +1,21 @@
 package roles
 
 import (
-	"bytes"
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/go-paxos/config"
 	"github.com/go-paxos/domain"
 	"github.com/go-paxos/logger"
+	"github.com/go-paxos/metrics"
+	"github.com/go-paxos/tracing"
 	"github.com/tryfix/log"
-	"io/ioutil"
-	"math/big"
 	"net/http"
-	"strconv"
 	"sync"
 	"time"
 )
 
-type SlotStatus int
-
-const (
-	ValidSlot = iota + 1
-	FutureSlot
-	InvalidSlot
-)
-
 // internal state structure of last promised and accepted proposals
 type state struct {
 	id   int
@@ -35,347 +24,381 @@ type state struct {
 }
 
 type Leader struct {
-	id       int
-	lastSlot int
-	promised state
-	accepted state
-	leaders  []string // excluding the current node
-	replicas []string
-	client   *http.Client
-	lock     *sync.RWMutex
-	logger   log.Logger
+	id         int
+	state      leaderState  // owned by the event loop, see run
+	events     chan message // messages handled by the event loop
+	members    *Membership
+	book       *AddressBook
+	outboxes   map[int]*outbox // decisions waiting to be delivered to each replica
+	outboxLock *sync.Mutex
+	inflight   map[string]*inflight // proposals of the requests with idempotency keys which are not completed yet
+	keyLock    *sync.Mutex
+	pipeline   chan struct{} // bounds the slots being proposed at a time to the reconfiguration window
+	recovery   chan struct{} // closed once the leader has recovered its state from the acceptors
+	done       chan struct{} // closed once the leader is closed, which stops the delivery loops of the outboxes
+	catchingUp int32         // set while the acceptor catches up with the decisions it has missed
+	client     *http.Client
+	logger     log.Logger
 }
 
-func NewLeader(hostname string, leaders, replicas []string, logger log.Logger) *Leader {
-	return &Leader{
-		id:       id(hostname),
-		lastSlot: -1,
-		promised: state{},
-		accepted: state{},
-		leaders:  leaders,
-		replicas: replicas,
-		client:   &http.Client{Timeout: time.Duration(domain.Config.LeaderTimeout) * time.Second},
-		lock:     &sync.RWMutex{},
-		logger:   logger,
+func NewLeader(id int, members *Membership, book *AddressBook, logger log.Logger) *Leader {
+	l := &Leader{
+		id:         id,
+		state:      leaderState{lastSlot: -1, decidedUpTo: -1, floor: -1, decisions: map[int]string{}, promises: map[int]state{}, accepts: map[int]state{}},
+		events:     make(chan message),
+		members:    members,
+		book:       book,
+		outboxes:   map[int]*outbox{},
+		outboxLock: &sync.Mutex{},
+		inflight:   map[string]*inflight{},
+		keyLock:    &sync.Mutex{},
+		pipeline:   make(chan struct{}, members.window),
+		recovery:   make(chan struct{}),
+		done:       make(chan struct{}),
+		client:     &http.Client{},
+		logger:     logger,
 	}
-}
+	go l.run()
 
-func id(hostname string) int {
-	sum := sha256.Sum256([]byte(hostname))
-	hexVal := hex.EncodeToString(sum[:])
-	n := new(big.Int)
-	n.SetString(hexVal, 16)
-
-	return int(n.Uint64() % idLimit)
+	return l
 }
 
 /* Proposer functions */
 
-// ValidateSlot validates the requested slot to decide whether it is old, upcoming or valid
-func (l *Leader) ValidateSlot(reqSlot int) (lastSlot int, status SlotStatus) {
-	l.lock.RLock()
-	defer l.lock.RUnlock()
-	if reqSlot > l.lastSlot+1 {
-		return l.lastSlot, FutureSlot
+// Propose decides the value requested by a replica for a slot assigned by the sequencer of the leader
+func (l *Leader) Propose(ctx context.Context, req domain.Request) (dec domain.Decision, ok bool, err error) {
+	select {
+	case <-l.recovery:
+	case <-ctx.Done():
+		return domain.Decision{}, false, logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s: %s`, errRecovering, ctx.Err())))
 	}
 
-	if l.lastSlot+1 != reqSlot {
-		return l.lastSlot, InvalidSlot
+	// an attempt of a request which is already being proposed waits for the earlier attempt instead of proposing the
+	// value again, while a collided fast round still has to be recovered by the attempt itself
+	if key, _ := domain.ParseKeyedValue(req.Val); key != `` && req.Collision == nil {
+		var p *inflight
+		var joined bool
+		if p, joined, err = l.join(ctx, key); err != nil {
+			return domain.Decision{}, false, logger.ErrorWithLine(err)
+		}
+		if joined {
+			return p.dec, true, nil
+		}
+		defer func() { l.complete(key, p, dec, ok && err == nil) }()
 	}
 
-	return l.lastSlot, ValidSlot
-}
-
-// Propose creates the proposal when a replica has requested this leader and carries out the consensus algorithm
-func (l *Leader) Propose(ctx context.Context, req domain.Request) (dec domain.Decision, ok bool, err error) {
-	prop, err := l.newProposal(req.SlotID, req.Val)
-	if err != nil {
-		return domain.Decision{}, false, logger.ErrorWithLine(err)
+	select {
+	case l.pipeline <- struct{}{}:
+	case <-ctx.Done():
+		return domain.Decision{}, false, logger.ErrorWithLine(ctx.Err())
 	}
+	defer func() { <-l.pipeline }()
 
-	resList, err := l.send(ctx, typePrepare, prop)
-	if err != nil {
-		return domain.Decision{}, false, logger.ErrorWithLine(err)
+	if req.Collision != nil {
+		dec, ok, err := l.recoverCollision(ctx, *req.Collision, req)
+		if err != nil {
+			return domain.Decision{}, false, logger.ErrorWithLine(err)
+		}
+		if ok {
+			return dec, true, nil
+		}
 	}
 
-	accepted, rejected, valid := l.validatePromises(resList)
-	if valid {
-		if accepted > rejected {
-			resList, err = l.send(ctx, typeAccept, prop)
-			if err != nil {
-				return domain.Decision{}, false, logger.ErrorWithLine(err)
-			}
+	for {
+		slot, err := l.assignSlot()
+		if err != nil {
+			return domain.Decision{}, false, logger.ErrorWithLine(err)
+		}
 
-			accepted, rejected = l.validateAccepts(resList)
-			if accepted > rejected {
-				l.logger.DebugContext(ctx, fmt.Sprintf(`requested value %s was proposed and chosen for slot %d`, req.Val, req.SlotID))
-				dec.SlotID = req.SlotID
-				dec.Val = req.Val
-
-				l.lock.Lock()
-				l.lastSlot++
-				l.lock.Unlock()
-
-				err = l.broadcastDecision(dec, req.Replica)
-				if err != nil {
-					return domain.Decision{}, false, logger.ErrorWithLine(err)
-				}
-				return dec, true, nil
-			}
+		if err = l.awaitConfiguration(ctx, slot); err == errGap {
+			l.unassignSlot(slot)
+			continue
+		} else if err != nil {
+			l.unassignSlot(slot)
+			return domain.Decision{}, false, logger.ErrorWithLine(err)
+		}
+
+		if !l.members.IsMember(slot, l.id) {
+			l.unassignSlot(slot)
+			return domain.Decision{}, false, logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (id: %d, slot: %d)`, errNotMember, l.id, slot)))
+		}
+
+		dec, ok, preempted, err := l.proposeSlot(ctx, slot, req)
+		if err != nil {
+			l.unassignSlot(slot)
+			return domain.Decision{}, false, logger.ErrorWithLine(err)
+		}
+
+		if ok {
+			return dec, true, nil
+		}
+
+		// a leader which forwards requests to the preempting leader leaves the value to it as well
+		if preempted && config.Get().ForwardPreempted {
+			return domain.Decision{}, false, nil
+		}
+
+		if err = ctx.Err(); err != nil {
+			return domain.Decision{}, false, logger.ErrorWithLine(err)
 		}
+		l.logger.DebugContext(ctx, fmt.Sprintf(`slot %d was taken by another proposal, proposing value %s for the next slot`, slot, req.Val))
 	}
+}
 
-	return domain.Decision{}, false, nil
+// inflight is the proposal of a request with an idempotency key which other attempts of the request wait for
+type inflight struct {
+	done chan struct{} // closed once the proposal is completed
+	dec  domain.Decision
+	ok   bool
 }
 
-// newProposal creates a proposal with an id in the format of `timestamp`+`leader_id`
-func (l *Leader) newProposal(slotID int, val string) (domain.Proposal, error) {
-	ts := time.Now().Unix()
-	pId, err := strconv.Atoi(fmt.Sprintf(`%d%d`, ts, l.id))
-	if err != nil {
-		return domain.Proposal{}, logger.ErrorWithLine(err)
+// join waits for the proposal of another attempt with the same key and reports whether its value was chosen
+func (l *Leader) join(ctx context.Context, key string) (p *inflight, joined bool, err error) {
+	for {
+		l.keyLock.Lock()
+		p, ok := l.inflight[key]
+		if !ok {
+			p = &inflight{done: make(chan struct{})}
+			l.inflight[key] = p
+			l.keyLock.Unlock()
+			return p, false, nil
+		}
+		l.keyLock.Unlock()
+
+		select {
+		case <-p.done:
+			if p.ok {
+				return p, true, nil
+			}
+		case <-ctx.Done():
+			return nil, false, logger.ErrorWithLine(ctx.Err())
+		}
 	}
+}
 
-	return domain.Proposal{ID: pId, SlotID: slotID, Val: val}, nil
+// complete records the outcome of the proposal of a key and releases the attempts waiting for it
+func (l *Leader) complete(key string, p *inflight, dec domain.Decision, ok bool) {
+	l.keyLock.Lock()
+	defer l.keyLock.Unlock()
+	delete(l.inflight, key)
+	p.dec, p.ok = dec, ok
+	close(p.done)
 }
 
-// Broadcasts the decision to all the replicas excluding the requested one
-func (l *Leader) broadcastDecision(dec domain.Decision, requester string) error {
-	data, err := json.Marshal(dec)
+// proposeSlot decides the value in the slot and reports whether it was chosen or preempted by another leader
+func (l *Leader) proposeSlot(ctx context.Context, slot int, req domain.Request) (dec domain.Decision, ok, preempted bool, err error) {
+	prop, promises, err := l.prepareRound(ctx, slot, req)
 	if err != nil {
-		return logger.ErrorWithLine(err)
+		return domain.Decision{}, false, false, logger.ErrorWithLine(err)
 	}
 
-	wg := &sync.WaitGroup{}
-	errChan := make(chan error, len(l.replicas))
-
-	for _, replica := range l.replicas {
-		if replica == requester {
-			continue
+	if promises.succeeded() || promises.free() {
+		if err = ctx.Err(); err != nil {
+			return domain.Decision{}, false, false, logger.ErrorWithLine(err)
 		}
 
-		wg.Add(1)
-		go func(replica string, wg *sync.WaitGroup, errChan chan error) {
-			defer wg.Done()
-			req, err := http.NewRequest(http.MethodPost, `http://`+replica+domain.UpdateReplicaEndpoint, bytes.NewBuffer(data))
-			if err != nil {
-				errChan <- logger.ErrorWithLine(err)
-				return
-			}
-
-			res, err := l.client.Do(req)
-			if err != nil {
-				errChan <- logger.ErrorWithLine(err)
-				return
-			}
+		dec, ok, err = l.decide(ctx, prop, req.From)
+		if err != nil {
+			return domain.Decision{}, false, false, logger.ErrorWithLine(err)
+		}
 
-			if res.StatusCode != http.StatusOK {
-				res.Body.Close()
-				errChan <- logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (status: %d)`, errBroadcast, res.StatusCode)))
-				return
-			}
-			errChan <- nil
-			res.Body.Close()
-		}(replica, wg, errChan)
+		if ok {
+			metrics.Proposals.Inc(metrics.LabelChosen)
+			return dec, true, false, nil
+		}
 	}
 
-	wg.Wait()
-	for i := 0; i < len(l.replicas)-1; i++ {
-		err = <-errChan
-		if err != nil {
-			return err
+	// a value accepted by some of the acceptors may already have been chosen, so it is completed on behalf of the leader
+	// (or the replica in a fast round) which has proposed it, as it may have given up on the slot after being preempted
+	// by this proposal
+	if adopted := promises.adopted(); promises.recoverable() && adopted.id != 0 {
+		prop.Val = adopted.val
+		if _, _, err = l.decide(ctx, prop, 0); err != nil {
+			return domain.Decision{}, false, false, logger.ErrorWithLine(err)
 		}
 	}
 
-	return nil
+	metrics.Proposals.Inc(metrics.LabelNotChosen)
+	return domain.Decision{}, false, promises.preempted, nil
 }
 
-// Sends out the proposal to all acceptors in both phases prepare and accept, excluding the current leader as it does not exist in leader list
-func (l *Leader) send(ctx context.Context, typ string, prop domain.Proposal) ([]domain.Acceptance, error) {
-	data, err := json.Marshal(prop)
+// decide carries out the accept phase of a promised proposal and delivers the decision to the other nodes
+func (l *Leader) decide(ctx context.Context, prop domain.Proposal, requester int) (dec domain.Decision, ok bool, err error) {
+	// once started, the accept phase is completed within its own timeout even if the requester gives up, since
+	// a proposal abandoned after some acceptors have accepted it would leave the slot undecidable
+	startedAt := time.Now()
+	accepts, err := l.send(tracing.Detach(ctx), typeAccept, prop)
 	if err != nil {
-		return nil, logger.ErrorWithLine(err)
+		return domain.Decision{}, false, logger.ErrorWithLine(err)
 	}
+	metrics.PhaseDuration.Observe(time.Since(startedAt).Seconds(), typeAccept)
 
-	var endpoint string
-	if typ == typePrepare {
-		endpoint = domain.PrepareEndpoint
-	} else {
-		endpoint = domain.AcceptEndpoint
+	if !accepts.succeeded() {
+		return domain.Decision{}, false, nil
 	}
 
-	var resList []domain.Acceptance
-	resChan := make(chan domain.Acceptance)
-	errChan := make(chan error)
-	wg := &sync.WaitGroup{}
-	for _, acceptor := range l.leaders {
-		wg.Add(1)
-		go func(acceptor string, resChan chan domain.Acceptance, wg *sync.WaitGroup, errChan chan error) {
-			req, err := http.NewRequest(http.MethodPost, `http://`+acceptor+endpoint, bytes.NewBuffer(data))
-			if err != nil {
-				errChan <- errors.New(fmt.Sprintf(`%s for acceptor: %s`, err.Error(), acceptor))
-				return
-			}
+	l.logger.DebugContext(ctx, fmt.Sprintf(`requested value %s was proposed and chosen for slot %d`, prop.Val, prop.SlotID))
+	dec.From = l.id
+	dec.SlotID = prop.SlotID
+	dec.Val = prop.Val
+	l.learned([]domain.Entry{{SlotID: dec.SlotID, Val: dec.Val}}, -1, nil)
 
-			res, err := l.client.Do(req)
-			if err != nil {
-				errChan <- errors.New(fmt.Sprintf(`%s for acceptor: %s`, err.Error(), acceptor))
-				return
-			}
+	// the requester receives the decision in the response unless it has given up waiting for it
+	if ctx.Err() != nil {
+		requester = 0
+	}
+	l.disseminate(ctx, dec, requester)
+	return dec, true, nil
+}
 
-			if res.StatusCode != http.StatusOK {
-				res.Body.Close()
-				errChan <- errors.New(fmt.Sprintf(`%s (type: %s, status: %d) for acceptor: %s`, errRequestAcceptor, typ, res.StatusCode, acceptor))
-				return
-			}
+// prepareRound carries out the prepare phase for the requested value in the given slot
+func (l *Leader) prepareRound(ctx context.Context, slot int, req domain.Request) (domain.Proposal, *round, error) {
+	conf := config.Get()
+	for attempt := 0; ; attempt++ {
+		prop, err := l.newProposal(slot, req.Val)
+		if err != nil {
+			return domain.Proposal{}, nil, logger.ErrorWithLine(err)
+		}
 
-			resData, err := ioutil.ReadAll(res.Body)
-			if err != nil {
-				res.Body.Close()
-				errChan <- errors.New(fmt.Sprintf(`%s for acceptor: %s`, err.Error(), acceptor))
-				return
-			}
-			res.Body.Close()
+		startedAt := time.Now()
+		promises, err := l.send(ctx, typePrepare, prop)
+		if err != nil {
+			return domain.Proposal{}, nil, logger.ErrorWithLine(err)
+		}
+		metrics.PhaseDuration.Observe(time.Since(startedAt).Seconds(), typePrepare)
 
-			var response domain.Acceptance
-			err = json.Unmarshal(resData, &response)
-			if err != nil {
-				errChan <- errors.New(fmt.Sprintf(`%s for acceptor: %s`, err.Error(), acceptor))
-				return
-			}
-			wg.Done()
-			resChan <- response
-		}(acceptor, resChan, wg, errChan)
-	}
+		if !promises.preempted {
+			return prop, promises, nil
+		}
 
-	wg.Wait()
-	for i := 0; i < len(l.leaders); i++ {
+		metrics.Preemptions.Inc()
+		l.preempted(promises.ballot)
+		// a leader which forwards requests to the preempting leader leaves the slot to it instead of retrying
+		if attempt == conf.PreemptionRetries || conf.ForwardPreempted {
+			return prop, promises, nil
+		}
+
+		wait := backoff(conf.PreemptionBackoff.Duration, conf.PreemptionBackoffMax.Duration, attempt)
+		l.logger.DebugContext(ctx, fmt.Sprintf(`proposal %d for slot %d was preempted by %d, retrying in %s`, prop.ID, prop.SlotID, promises.ballot, wait))
 		select {
-		case res := <-resChan:
-			resList = append(resList, res)
-		case err = <-errChan:
-			l.logger.ErrorContext(ctx, err)
-		default:
-			continue
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return domain.Proposal{}, nil, logger.ErrorWithLine(ctx.Err())
 		}
 	}
-
-	return resList, nil
 }
 
-// Validates promises upon receiving them from acceptors and returns number of accepted and rejected cases. This function
-// returns false for valid if a different proposer has already started a proposal with a higher id.
-func (l *Leader) validatePromises(resList []domain.Acceptance) (accepted, rejected int, valid bool) {
-	accepted, rejected = 0, 0
-	for _, promise := range resList {
-		if promise.PrvAccept.Exists {
-			if promise.PrvAccept.ID >= promise.PID {
-				return accepted, rejected, false
-			}
-			rejected++
-			continue
-		}
-
-		if promise.PrvPromise.Exists {
-			if promise.PrvPromise.ID >= promise.PID {
-				return accepted, rejected, false
-			}
-			rejected++
-			continue
-		}
-		accepted++
+// awaitConfiguration waits until all slots up to the reconfiguration window below the slot are decided
+func (l *Leader) awaitConfiguration(ctx context.Context, slot int) error {
+	select {
+	case <-l.decided(slot - l.members.window):
+		return nil
+	case <-time.After(config.Get().GapTimeout.Duration):
+	case <-ctx.Done():
+		return logger.ErrorWithLine(ctx.Err())
 	}
 
-	return accepted, rejected, true
+	if err := l.fillGaps(ctx, slot-l.members.window); err != nil {
+		return logger.ErrorWithLine(err)
+	}
+	return errGap
 }
 
-// Validates accept responses and returns the accepted and rejected cases
-func (l *Leader) validateAccepts(resList []domain.Acceptance) (accepted, rejected int) {
-	accepted, rejected = 0, 0
-	for _, accept := range resList {
-		if accept.Accepted {
-			accepted++
-			continue
-		}
-		rejected++
+// newProposal creates a proposal with an id which is higher than the ids which have preempted the proposer
+func (l *Leader) newProposal(slotID int, val string) (domain.Proposal, error) {
+	reply := make(chan proposeReply, 1)
+	l.events <- proposeMsg{now: time.Now().Unix(), reply: reply}
+	r := <-reply
+	if r.err != nil {
+		return domain.Proposal{}, r.err
 	}
 
-	return accepted, rejected
+	return domain.Proposal{From: l.id, ID: r.id, SlotID: slotID, Val: val}, nil
 }
 
-/* Acceptor functions */
+// Sends out the proposal to all acceptors of the slot and returns as soon as the outcome of the phase is known
+func (l *Leader) send(ctx context.Context, typ string, prop domain.Proposal) (*round, error) {
+	parent := ctx
+	ctx, span := tracing.Start(ctx, `leader.`+typ)
+	span.SetAttribute(`slot`, prop.SlotID)
+	span.SetAttribute(`proposal_id`, prop.ID)
+	defer span.End()
 
-// HandlePrepare handles prepare message requested by a proposer to check if this acceptor has already promised or accepted a proposal
-func (l *Leader) HandlePrepare(prop domain.Proposal) (domain.Acceptance, error) {
-	var res domain.Acceptance
-	res.PID = prop.ID
-	l.lock.Lock()
-	defer l.lock.Unlock()
-
-	// returns an error if the proposal is for an older slot
-	if l.accepted.slot > prop.SlotID {
-		return domain.Acceptance{}, logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (phase: %s, last: %d, requested: %d)`,
-			errInvalidProposal, typePrepare, l.accepted.slot, prop.SlotID)))
+	data, err := json.Marshal(prop)
+	if err != nil {
+		return nil, logger.ErrorWithLine(err)
+	}
+
+	timeout := config.Get().PrepareTimeout.Duration
+	if typ == typeAccept {
+		timeout = config.Get().AcceptTimeout.Duration
 	}
 
-	if l.promised.slot == prop.SlotID {
-		// check if promised id is higher than the requested one since proposer will use this to terminate its proposal
-		if l.promised.id >= prop.ID {
-			res.PrvPromise.Exists = true
-			res.PrvPromise.ID = l.promised.id
-			res.PrvPromise.Val = l.promised.val
-		} else {
-			// as the requested prepare is valid, acceptor updates its state for the same slot
-			l.promised.id = prop.ID
-			l.promised.val = prop.Val
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	request := func(ctx context.Context, acceptor int) (domain.Acceptance, error) {
+		return l.request(ctx, typ, acceptor, prop, data)
+	}
+	rnd, err := fanOut(ctx, typ, prop.SlotID, l.members.Votes(prop.SlotID), request, l.logger.ErrorContext)
+	if err != nil {
+		if parentErr := parent.Err(); parentErr != nil {
+			return nil, logger.ErrorWithLine(parentErr)
 		}
-	} else {
-		// if the prepare request is for a new slot
-		l.promised.id = prop.ID
-		l.promised.slot = prop.SlotID
-		l.promised.val = prop.Val
+		return nil, logger.ErrorWithLine(err)
 	}
 
-	// if there's an already accepted proposal for the same slot, acceptor just notifies the proposer
-	if l.accepted.slot == prop.SlotID && l.accepted.id != 0 {
-		res.PrvAccept.Exists = true
-		res.PrvAccept.ID = l.accepted.id
-		res.PrvAccept.Val = l.accepted.val
+	span.SetAttribute(`accepted`, rnd.accepted)
+	span.SetAttribute(`rejected`, rnd.rejected)
+	if !rnd.reachable() {
+		return nil, logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (phase: %s, slot: %d, failed votes: %d, quorum: %d)`,
+			errNoQuorum, typ, prop.SlotID, rnd.failed, rnd.quorum)))
 	}
 
-	return res, nil
+	return rnd, nil
 }
 
-// HandleAccept checks if it can accept the confirmation request from a proposer
-func (l *Leader) HandleAccept(prop domain.Proposal) (domain.Acceptance, error) {
-	// returns an error if the proposal is for an older slot
-	if l.accepted.slot > prop.SlotID {
-		return domain.Acceptance{}, logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (phase: %s, last: %d, requested: %d)`,
-			errInvalidProposal, typeAccept, l.accepted.slot, prop.SlotID)))
+// request sends the proposal to a single acceptor in the given phase
+func (l *Leader) request(ctx context.Context, typ string, acceptor int, prop domain.Proposal, data []byte) (domain.Acceptance, error) {
+	if acceptor == l.id {
+		handle := l.HandleAccept
+		if typ == typePrepare {
+			handle = l.HandlePrepare
+		}
+		res, err := handle(prop)
+		if err != nil {
+			return domain.Acceptance{}, errStale
+		}
+		return res, nil
 	}
 
-	var res domain.Acceptance
-	res.PID = prop.ID
-	l.lock.Lock()
-	defer l.lock.Unlock()
+	return requestAcceptor(ctx, l.client, l.book, typ, acceptor, data)
+}
 
-	// rejects if already promised to a proposal with a higher id for the same slot
-	if l.promised.slot == prop.SlotID && l.promised.id > prop.ID {
-		res.Accepted = false
-		return res, nil
-	}
+/* Acceptor functions */
 
-	// rejects if already accepted for the same slot
-	if l.accepted.slot == prop.SlotID && l.accepted.id != 0 {
-		res.Accepted = false
-		return res, nil
-	}
+// HandlePrepare handles prepare message requested by a proposer to check if this acceptor has already promised or accepted a proposal
+func (l *Leader) HandlePrepare(prop domain.Proposal) (domain.Acceptance, error) {
+	reply := make(chan acceptorReply, 1)
+	l.events <- prepareMsg{prop: prop, reply: reply}
+	r := <-reply
+	return r.res, r.err
+}
 
-	l.accepted.id = prop.ID
-	l.accepted.val = prop.Val
-	l.accepted.slot = prop.SlotID
-	l.lastSlot++
-	res.Accepted = true
+// HandleAccept checks if it can accept the confirmation request from a proposer
+func (l *Leader) HandleAccept(prop domain.Proposal) (domain.Acceptance, error) {
+	reply := make(chan acceptorReply, 1)
+	l.events <- acceptMsg{prop: prop, reply: reply}
+	r := <-reply
+	return r.res, r.err
+}
 
-	return res, nil
+// Close stops the leader from initiating further proposals and waits for the queued decisions to be delivered
+func (l *Leader) Close(ctx context.Context) {
+	reply := make(chan leaderState, 1)
+	l.events <- closeMsg{reply: reply}
+	st := <-reply
+	close(l.done)
+	l.drain(ctx)
+	l.logger.InfoContext(ctx, fmt.Sprintf(`leader closed (last slot: %d)`, st.lastSlot))
 }
@@ -0,0 +1,425 @@
+package roles
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-paxos/config"
+	"github.com/go-paxos/domain"
+	"github.com/go-paxos/logger"
+	"github.com/go-paxos/metrics"
+	"sort"
+)
+
+// leaderState is the state of the proposer and acceptor of a leader, which is owned by the event loop of the leader
+type leaderState struct {
+	lastSlot    int            // highest slot known to be decided
+	decidedUpTo int            // all slots up to this one are known to be decided
+	floor       int            // slots up to this one are garbage collected along with their decided values
+	decisions   map[int]string // decided values of the slots above the floor
+	next        int            // next slot to be assigned by the sequencer
+	free        []int          // assigned slots which were given back without being decided, in ascending order
+	promises    map[int]state  // promised proposals by slot, as several slots may be proposed at a time
+	accepts     map[int]state  // accepted proposals by slot
+	promised    state          // latest promised proposal
+	accepted    state          // latest accepted proposal
+	proposed    int            // id of the latest proposal of the proposer
+	ballot      int            // highest proposal id which has preempted the proposer
+	waiters     []waiter       // proposers waiting for the decided-up-to watermark to reach a slot
+	closed      bool
+}
+
+// waiter is released once all slots up to the given one are decided
+type waiter struct {
+	upTo  int
+	reply chan struct{}
+}
+
+// message is an event handled by the event loop of the leader, which replies through a buffered channel
+type message interface{}
+
+type (
+	// prepareMsg and acceptMsg are the requests of a proposer to the acceptor in either phase
+	prepareMsg struct {
+		prop  domain.Proposal
+		reply chan acceptorReply
+	}
+
+	acceptMsg struct {
+		prop  domain.Proposal
+		reply chan acceptorReply
+	}
+
+	// proposeMsg allocates the id of a new proposal, which is refused once the leader is closed
+	proposeMsg struct {
+		now   int64
+		reply chan proposeReply
+	}
+
+	// assignMsg assigns a slot to a request, which is refused once the leader is closed
+	assignMsg struct {
+		reply chan assignReply
+	}
+
+	// unassignMsg gives back an assigned slot which was not decided by the leader
+	unassignMsg struct {
+		slot  int
+		reply chan struct{}
+	}
+
+	// acceptorStateMsg reads the proposals promised, accepted and decided from the given slot for a recovering leader
+	acceptorStateMsg struct {
+		from  int
+		reply chan domain.AcceptorState
+	}
+
+	// decisionLogMsg reads the decided values from the given slot for a node catching up
+	decisionLogMsg struct {
+		from  int
+		reply chan domain.DecisionLog
+	}
+
+	// recoveredMsg restores the state of the proposer from the state of a quorum of acceptors
+	recoveredMsg struct {
+		next   int
+		ballot int
+		free   []int
+		reply  chan struct{}
+	}
+
+	// preemptedMsg records the proposal id which has preempted a proposal of the proposer
+	preemptedMsg struct {
+		ballot int
+		reply  chan struct{}
+	}
+
+	// decidedMsg records the decided values of the given entries, and that all slots up to the given one are decided
+	// even if their values are not known to the leader, along with the configurations decided in those slots
+	decidedMsg struct {
+		entries []domain.Entry
+		upTo    int
+		configs []domain.Configuration
+		reply   chan struct{}
+	}
+
+	// awaitMsg waits for all slots up to the given one to be decided
+	awaitMsg struct {
+		upTo  int
+		reply chan struct{}
+	}
+
+	// snapshotMsg reads a copy of the state
+	snapshotMsg struct {
+		reply chan leaderState
+	}
+
+	// closeMsg stops the leader from starting further proposals
+	closeMsg struct {
+		reply chan leaderState
+	}
+)
+
+type acceptorReply struct {
+	res domain.Acceptance
+	err error
+}
+
+type proposeReply struct {
+	id  int
+	err error
+}
+
+type assignReply struct {
+	slot int
+	err  error
+}
+
+// run handles the messages sent to the leader one at a time as the only goroutine which accesses the leader state
+func (l *Leader) run() {
+	for msg := range l.events {
+		switch m := msg.(type) {
+		case prepareMsg:
+			res, err := l.prepare(m.prop)
+			m.reply <- acceptorReply{res: res, err: err}
+		case acceptMsg:
+			res, err := l.accept(m.prop)
+			m.reply <- acceptorReply{res: res, err: err}
+		case proposeMsg:
+			if l.state.closed {
+				m.reply <- proposeReply{err: logger.ErrorWithLine(errors.New(errClosed))}
+				continue
+			}
+			m.reply <- proposeReply{id: l.propose(m.now)}
+		case assignMsg:
+			if l.state.closed {
+				m.reply <- assignReply{err: logger.ErrorWithLine(errors.New(errClosed))}
+				continue
+			}
+			m.reply <- assignReply{slot: l.assign()}
+		case unassignMsg:
+			l.unassign(m.slot)
+			m.reply <- struct{}{}
+		case acceptorStateMsg:
+			m.reply <- l.acceptorState(m.from)
+		case decisionLogMsg:
+			m.reply <- l.decisionLog(m.from)
+		case recoveredMsg:
+			if m.next > l.state.next {
+				l.state.next = m.next
+			}
+			if m.ballot > l.state.ballot {
+				l.state.ballot = m.ballot
+			}
+			for _, slot := range m.free {
+				l.unassign(slot)
+			}
+			m.reply <- struct{}{}
+		case preemptedMsg:
+			if m.ballot > l.state.ballot {
+				l.state.ballot = m.ballot
+			}
+			m.reply <- struct{}{}
+		case decidedMsg:
+			for _, entry := range m.entries {
+				l.learn(entry.SlotID, entry.Val)
+			}
+			l.skip(m.upTo, m.configs)
+			m.reply <- struct{}{}
+		case awaitMsg:
+			if m.upTo <= l.state.decidedUpTo {
+				m.reply <- struct{}{}
+				continue
+			}
+			l.state.waiters = append(l.state.waiters, waiter{upTo: m.upTo, reply: m.reply})
+		case snapshotMsg:
+			m.reply <- l.copyState()
+		case closeMsg:
+			l.state.closed = true
+			m.reply <- l.copyState()
+		}
+		metrics.AcceptorSlots.Set(float64(len(l.state.promises) + len(l.state.accepts) + len(l.state.decisions)))
+	}
+}
+
+// copyState returns a copy of the state without its maps and slices and should only be called by the event loop
+func (l *Leader) copyState() leaderState {
+	st := l.state
+	st.decisions, st.promises, st.accepts, st.free, st.waiters = nil, nil, nil, nil, nil
+	return st
+}
+
+// propose returns the id of a new proposal as `round`+`leader_id` and should only be called by the event loop
+func (l *Leader) propose(now int64) int {
+	round := int(now)
+	if preempted := l.state.ballot / (domain.MaxNodeID + 1); round <= preempted {
+		round = preempted + 1
+	}
+
+	l.state.proposed = round*(domain.MaxNodeID+1) + l.id
+	return l.state.proposed
+}
+
+// assign returns the lowest slot given back or else the next slot, and should only be called by the event loop
+func (l *Leader) assign() int {
+	s := &l.state
+	for len(s.free) > 0 {
+		slot := s.free[0]
+		s.free = s.free[1:]
+		if !l.isDecided(slot) {
+			return slot
+		}
+	}
+
+	if s.next <= s.lastSlot {
+		s.next = s.lastSlot + 1
+	}
+	s.next++
+	return s.next - 1
+}
+
+// unassign gives back a slot for the next request and should only be called by the event loop
+func (l *Leader) unassign(slot int) {
+	s := &l.state
+	i := sort.SearchInts(s.free, slot)
+	if i < len(s.free) && s.free[i] == slot {
+		return
+	}
+	s.free = append(s.free, 0)
+	copy(s.free[i+1:], s.free[i:])
+	s.free[i] = slot
+}
+
+// prepare promises the proposal unless a higher one is promised for the slot and should only be called by the event loop
+func (l *Leader) prepare(prop domain.Proposal) (domain.Acceptance, error) {
+	var res domain.Acceptance
+	res.From = l.id
+	res.PID = prop.ID
+	s := &l.state
+
+	// returns an error if the slot has already been decided, so that it is not decided again with another value
+	if l.isDecided(prop.SlotID) {
+		return domain.Acceptance{}, logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (phase: %s, slot: %d, decided up to: %d)`,
+			errSlotDecided, typePrepare, prop.SlotID, s.decidedUpTo)))
+	}
+
+	// check if promised id is higher than the requested one since proposer will use this to terminate its proposal
+	if promised, ok := s.promises[prop.SlotID]; ok && promised.id >= prop.ID {
+		res.PrvPromise.Exists = true
+		res.PrvPromise.ID = promised.id
+		res.PrvPromise.Val = promised.val
+	} else {
+		// as the requested prepare is valid, acceptor updates its state for the slot
+		s.promises[prop.SlotID] = state{id: prop.ID, slot: prop.SlotID, val: prop.Val}
+		s.promised = s.promises[prop.SlotID]
+	}
+
+	// if there's an already accepted proposal for the same slot, acceptor just notifies the proposer
+	if accepted, ok := s.accepts[prop.SlotID]; ok {
+		res.PrvAccept.Exists = true
+		res.PrvAccept.ID = accepted.id
+		res.PrvAccept.Val = accepted.val
+	}
+
+	return res, nil
+}
+
+// accept accepts the proposal unless a higher one is promised for the slot and should only be called by the event loop
+func (l *Leader) accept(prop domain.Proposal) (domain.Acceptance, error) {
+	var res domain.Acceptance
+	res.From = l.id
+	res.PID = prop.ID
+	s := &l.state
+
+	if l.isDecided(prop.SlotID) {
+		return domain.Acceptance{}, logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (phase: %s, slot: %d, decided up to: %d)`,
+			errSlotDecided, typeAccept, prop.SlotID, s.decidedUpTo)))
+	}
+
+	if prop.ID == domain.FastBallot {
+		return l.acceptFast(prop)
+	}
+
+	// rejects if already promised to a proposal with a higher id for the same slot
+	if promised, ok := s.promises[prop.SlotID]; ok && promised.id > prop.ID {
+		res.Accepted = false
+		return res, nil
+	}
+
+	s.accepts[prop.SlotID] = state{id: prop.ID, slot: prop.SlotID, val: prop.Val}
+	s.accepted = s.accepts[prop.SlotID]
+	res.Accepted = true
+
+	return res, nil
+}
+
+// acceptFast casts the single vote of the acceptor in the fast round of the slot and should only be called by the event loop
+func (l *Leader) acceptFast(prop domain.Proposal) (domain.Acceptance, error) {
+	res := domain.Acceptance{From: l.id, PID: prop.ID}
+	s := &l.state
+
+	if !config.Get().FastPaxos {
+		return domain.Acceptance{}, logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (slot: %d)`, errFastDisabled, prop.SlotID)))
+	}
+
+	if _, ok := s.promises[prop.SlotID]; ok {
+		return res, nil
+	}
+	if accepted, ok := s.accepts[prop.SlotID]; ok && (accepted.id != prop.ID || accepted.val != prop.Val) {
+		return res, nil
+	}
+
+	s.accepts[prop.SlotID] = state{id: prop.ID, slot: prop.SlotID, val: prop.Val}
+	res.Accepted = true
+	return res, nil
+}
+
+// acceptorState returns the proposals and decisions of the acceptor from the given slot and should only be called by the event loop
+func (l *Leader) acceptorState(from int) domain.AcceptorState {
+	st := domain.AcceptorState{
+		From:        l.id,
+		LastSlot:    l.state.lastSlot,
+		DecidedUpTo: l.state.decidedUpTo,
+		Ballot:      l.state.promised.id,
+		Accepted:    []domain.SlotState{},
+		Decided:     l.decisionEntries(from, -1),
+		// lets a recovering leader adopt the configurations decided in the slots it skips
+		Configurations: l.members.Configurations(),
+	}
+	// the latest accepted proposal is kept after the state of its slot is discarded once the slot is decided
+	if l.state.accepted.id > st.Ballot {
+		st.Ballot = l.state.accepted.id
+	}
+	for _, promised := range l.state.promises {
+		if promised.id > st.Ballot {
+			st.Ballot = promised.id
+		}
+	}
+
+	for _, accepted := range l.state.accepts {
+		if accepted.id > st.Ballot {
+			st.Ballot = accepted.id
+		}
+		if accepted.slot >= from {
+			st.Accepted = append(st.Accepted, domain.SlotState{ID: accepted.id, Slot: accepted.slot, Val: accepted.val})
+		}
+	}
+	sort.Slice(st.Accepted, func(i, j int) bool { return st.Accepted[i].Slot < st.Accepted[j].Slot })
+
+	return st
+}
+
+// advance moves the last slot up to the given slot. Should only be called by the event loop.
+func (l *Leader) advance(slot int) {
+	if slot > l.state.lastSlot {
+		l.state.lastSlot = slot
+	}
+	metrics.SlotIndex.Set(float64(l.state.lastSlot), metrics.LabelLeader)
+}
+
+/* Message senders */
+
+func (l *Leader) snapshot() leaderState {
+	reply := make(chan leaderState, 1)
+	l.events <- snapshotMsg{reply: reply}
+	return <-reply
+}
+
+// lastSlot returns the last slot known to be decided by the leader
+func (l *Leader) lastSlot() int {
+	return l.snapshot().lastSlot
+}
+
+// assignSlot returns the slot assigned to a new request by the sequencer of the leader
+func (l *Leader) assignSlot() (int, error) {
+	reply := make(chan assignReply, 1)
+	l.events <- assignMsg{reply: reply}
+	r := <-reply
+	return r.slot, r.err
+}
+
+// unassignSlot gives back a slot which was assigned to a request but not decided by the leader
+func (l *Leader) unassignSlot(slot int) {
+	reply := make(chan struct{}, 1)
+	l.events <- unassignMsg{slot: slot, reply: reply}
+	<-reply
+}
+
+// recovered restores the next slot and the ballot of the leader, and gives back the slots which are known to be free
+func (l *Leader) recovered(next, ballot int, free []int) {
+	reply := make(chan struct{}, 1)
+	l.events <- recoveredMsg{next: next, ballot: ballot, free: free, reply: reply}
+	<-reply
+}
+
+// learned records the decided entries, and the slots and configurations decided up to the given slot
+func (l *Leader) learned(entries []domain.Entry, upTo int, configs []domain.Configuration) {
+	reply := make(chan struct{}, 1)
+	l.events <- decidedMsg{entries: entries, upTo: upTo, configs: configs, reply: reply}
+	<-reply
+}
+
+// decided returns a channel which is notified once all slots up to the given one are decided
+func (l *Leader) decided(upTo int) <-chan struct{} {
+	reply := make(chan struct{}, 1)
+	l.events <- awaitMsg{upTo: upTo, reply: reply}
+	return reply
+}
@@ -0,0 +1,167 @@
+package roles
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-paxos/domain"
+	"github.com/go-paxos/logger"
+	"sort"
+	"sync"
+)
+
+// Membership keeps the configurations of the cluster which are decided through the replicated log
+type Membership struct {
+	window  int
+	configs []domain.Configuration // ordered by the slot from which each configuration takes effect
+	book    *AddressBook
+	lock    *sync.RWMutex
+}
+
+func NewMembership(topology domain.Topology, window int, book *AddressBook) *Membership {
+	return &Membership{
+		window:  window,
+		configs: []domain.Configuration{{Epoch: 0, Decided: -1, Slot: 0, Nodes: topology}},
+		book:    book,
+		lock:    &sync.RWMutex{},
+	}
+}
+
+// At returns the configuration which decides the given slot
+func (m *Membership) At(slot int) domain.Configuration {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.at(slot)
+}
+
+func (m *Membership) at(slot int) domain.Configuration {
+	conf := m.configs[0]
+	for _, c := range m.configs[1:] {
+		if c.Slot > slot {
+			break
+		}
+		conf = c
+	}
+
+	return conf
+}
+
+// IDs returns the ids of the members with given role which decide the given slot, excluding the node with given id
+func (m *Membership) IDs(slot int, role string, exclude int) []int {
+	var ids []int
+	for _, node := range m.At(slot).Nodes {
+		if node.Role == role && node.ID != exclude {
+			ids = append(ids, node.ID)
+		}
+	}
+
+	return ids
+}
+
+// Votes returns the votes of each acceptor of the configuration which decides the given slot
+func (m *Membership) Votes(slot int) map[int]int {
+	votes := map[int]int{}
+	for _, node := range m.At(slot).Nodes {
+		if node.Role == domain.RoleLeader {
+			votes[node.ID] = node.Votes()
+		}
+	}
+
+	return votes
+}
+
+// IsMember checks if the node with given id is a member of the configuration which decides the given slot
+func (m *Membership) IsMember(slot, id int) bool {
+	_, ok := m.At(slot).Nodes.Node(id)
+	return ok
+}
+
+// Latest returns the most recently decided configuration which may not have taken effect yet
+func (m *Membership) Latest() domain.Configuration {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.configs[len(m.configs)-1]
+}
+
+// Apply registers the reconfiguration carried by a value decided at the given slot
+func (m *Membership) Apply(slot int, val string) (rc domain.Reconfiguration, applied bool, err error) {
+	if !domain.IsReconfiguration(val) {
+		return rc, false, nil
+	}
+
+	rc, err = domain.ParseReconfiguration(val)
+	if err != nil {
+		return rc, false, logger.ErrorWithLine(err)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	latest := m.configs[len(m.configs)-1]
+	if latest.Decided >= slot {
+		return rc, false, nil
+	}
+
+	// a node which has joined the cluster starts with a topology which already reflects its own addition
+	if node, ok := latest.Nodes.Node(rc.Node.ID); ok && rc.Op == domain.ReconfigAdd && node.Role == rc.Node.Role && node.Address == rc.Node.Address {
+		return rc, false, nil
+	}
+
+	nodes, err := latest.Nodes.Apply(rc)
+	if err != nil {
+		return rc, false, logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (slot: %d): %s`, errInvalidReconfig, slot, err)))
+	}
+
+	m.configs = append(m.configs, domain.Configuration{
+		Epoch:   latest.Epoch + 1,
+		Decided: slot,
+		Slot:    slot + m.window,
+		Nodes:   nodes,
+	})
+
+	if rc.Op == domain.ReconfigAdd {
+		m.book.Add(rc.Node)
+	}
+
+	return rc, true, nil
+}
+
+// Configurations returns all the configurations known to the member in the order they take effect
+func (m *Membership) Configurations() []domain.Configuration {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return append([]domain.Configuration{}, m.configs...)
+}
+
+// Adopt registers the configurations of another member which were decided up to the given slot
+func (m *Membership) Adopt(configs []domain.Configuration, upTo int) {
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Decided < configs[j].Decided })
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, c := range configs {
+		latest := m.configs[len(m.configs)-1]
+		if c.Decided <= latest.Decided || c.Decided > upTo {
+			continue
+		}
+
+		m.configs = append(m.configs, domain.Configuration{Epoch: latest.Epoch + 1, Decided: c.Decided, Slot: c.Slot, Nodes: c.Nodes})
+		for _, node := range c.Nodes {
+			m.book.Add(node)
+		}
+	}
+}
+
+// Status returns the configuration which decides the given slot along with the ones which take effect later
+func (m *Membership) Status(slot int) domain.Membership {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	status := domain.Membership{Window: m.window, Current: m.at(slot), Pending: []domain.Configuration{}}
+	for _, c := range m.configs {
+		if c.Slot > slot {
+			status.Pending = append(status.Pending, c)
+		}
+	}
+
+	return status
+}
@@ -0,0 +1,92 @@
+package roles
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/go-paxos/config"
+	"github.com/go-paxos/domain"
+	"github.com/go-paxos/logger"
+	"github.com/go-paxos/metrics"
+	"github.com/go-paxos/tracing"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// backoff returns a random duration within a window which starts at base and doubles with every attempt up to max
+func backoff(base, max time.Duration, attempt int) time.Duration {
+	window := base
+	for i := 0; i < attempt && window < max; i++ {
+		window *= 2
+	}
+	if window > max {
+		window = max
+	}
+
+	return time.Duration(rand.Int63n(int64(window)) + 1)
+}
+
+// preempted records the proposal id which has preempted a proposal of the leader
+func (l *Leader) preempted(ballot int) {
+	reply := make(chan struct{}, 1)
+	l.events <- preemptedMsg{ballot: ballot, reply: reply}
+	<-reply
+}
+
+// ForwardTarget returns the leader of the highest ballot which has preempted this leader, if any
+func (l *Leader) ForwardTarget() (int, bool) {
+	st := l.snapshot()
+	target := st.ballot % (domain.MaxNodeID + 1)
+	if st.ballot <= st.proposed || target == l.id || !l.members.IsMember(st.lastSlot+1, target) {
+		return 0, false
+	}
+
+	return target, true
+}
+
+// Forward relays a request of a replica to the given leader and returns the status and the body of its response
+func (l *Leader) Forward(ctx context.Context, leader int, req domain.Request) (status int, body []byte, err error) {
+	ctx, span := tracing.Start(ctx, `leader.forward`)
+	span.SetAttribute(`leader`, leader)
+	defer func() {
+		span.SetError(err)
+		span.End()
+		if err != nil {
+			metrics.ForwardedRequests.Inc(metrics.LabelFailed)
+			return
+		}
+		metrics.ForwardedRequests.Inc(metrics.LabelForwarded)
+	}()
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return 0, nil, logger.ErrorWithLine(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, config.Get().ReplicaTimeout.Duration)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, `http://`+l.book.Address(leader)+domain.RequestLeaderEndpoint, bytes.NewBuffer(data))
+	if err != nil {
+		return 0, nil, logger.ErrorWithLine(err)
+	}
+	tracing.Inject(ctx, httpReq)
+	domain.InjectTimeout(ctx, httpReq)
+	// the leader receiving a forwarded request proposes it by itself instead of forwarding it again
+	httpReq.Header.Set(domain.ForwardedHeader, strconv.Itoa(l.id))
+
+	res, err := l.client.Do(httpReq)
+	if err != nil {
+		return 0, nil, logger.ErrorWithLine(err)
+	}
+	defer res.Body.Close()
+
+	body, err = ioutil.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, logger.ErrorWithLine(err)
+	}
+
+	return res.StatusCode, body, nil
+}
@@ -0,0 +1,174 @@
+package roles
+
+import (
+	"errors"
+	"github.com/go-paxos/config"
+	"github.com/go-paxos/domain"
+	"github.com/go-paxos/metrics"
+)
+
+// errStale is the response of an acceptor which has refused to consider the proposal
+var errStale = errors.New(errInvalidProposal)
+
+// errGap is returned to a proposer which has recovered the undecided slots below the one it waits for
+var errGap = errors.New(errUndecidedGap)
+
+// response is the outcome of a request sent to a single acceptor in either phase
+type response struct {
+	acceptor int
+	res      domain.Acceptance
+	err      error
+}
+
+// round tallies the votes of the acceptors of a slot in a single phase
+type round struct {
+	typ       string
+	votes     map[int]int // votes of each acceptor
+	acceptors int         // total votes of the acceptors
+	quorum    int
+	accepted  int
+	rejected  int
+	failed    int
+	preempted bool
+	ballot    int            // highest proposal id which has preempted the round
+	priors    int            // votes of the acceptors which have promised the proposal but already accepted an earlier one
+	prior     state          // proposal with the highest id accepted by the acceptors which have promised
+	fast      int            // size of the fast quorums in votes
+	fastVotes map[string]int // votes of the acceptors which have promised for each value accepted in the fast round
+}
+
+func newRound(typ string, votes map[int]int) *round {
+	total := 0
+	for _, v := range votes {
+		total += v
+	}
+
+	phase1, phase2, _ := quorums(total)
+	r := &round{typ: typ, votes: votes, acceptors: total, quorum: phase1, fastVotes: map[string]int{}}
+	if config.Get().FastPaxos {
+		r.fast = fastQuorum(total)
+	}
+	switch typ {
+	case typeAccept:
+		r.quorum = phase2
+	case typeFast:
+		r.quorum = r.fast
+	}
+	return r
+}
+
+// quorums returns the sizes of the prepare and accept quorums in votes among the given votes of the acceptors
+func quorums(votes int) (phase1, phase2 int, widened bool) {
+	conf := config.Get()
+	return domain.Quorums(votes, conf.Phase1Quorum, conf.Phase2Quorum)
+}
+
+// fastQuorum returns the size of the fast quorums in votes among the given votes of the acceptors
+func fastQuorum(votes int) int {
+	phase1, _, _ := quorums(votes)
+	return domain.FastQuorum(votes, phase1, config.Get().FastQuorum)
+}
+
+// add counts the response of an acceptor
+func (r *round) add(res response) {
+	votes := r.votes[res.acceptor]
+	if res.err == errStale {
+		if r.typ == typePrepare {
+			metrics.Promises.Inc(metrics.LabelRejected)
+		}
+		r.rejected += votes
+		return
+	}
+
+	if res.err != nil {
+		r.failed += votes
+		return
+	}
+
+	if r.typ == typeAccept || r.typ == typeFast {
+		if res.res.Accepted {
+			r.accepted += votes
+			return
+		}
+		r.rejected += votes
+		return
+	}
+
+	// a higher promise is checked first, since the acceptor may have accepted a lower proposal before promising it
+	promise, prv := res.res.PrvPromise, res.res.PrvAccept
+	switch {
+	case promise.Exists && promise.ID >= res.res.PID:
+		r.preempt(promise.ID)
+	case !promise.Exists && !prv.Exists:
+		metrics.Promises.Inc(metrics.LabelAccepted)
+		r.accepted += votes
+	case prv.Exists && prv.ID >= res.res.PID:
+		r.preempt(prv.ID)
+	default:
+		metrics.Promises.Inc(metrics.LabelRejected)
+		r.rejected += votes
+		if !promise.Exists {
+			r.priors += votes
+			if prv.ID > r.prior.id {
+				r.prior = state{id: prv.ID, val: prv.Val}
+			}
+			if prv.ID == domain.FastBallot {
+				r.fastVotes[prv.Val] += votes
+			}
+		}
+	}
+}
+
+// preempt marks the round as preempted by a proposal with the given id
+func (r *round) preempt(id int) {
+	metrics.Promises.Inc(metrics.LabelPreempted)
+	r.preempted = true
+	if id > r.ballot {
+		r.ballot = id
+	}
+}
+
+// succeeded checks if a quorum of acceptors has accepted the proposal
+func (r *round) succeeded() bool {
+	return !r.preempted && r.accepted >= r.quorum
+}
+
+// recoverable checks if a quorum of acceptors has promised the proposal, regardless of what they have accepted
+func (r *round) recoverable() bool {
+	return !r.preempted && r.accepted+r.priors >= r.quorum
+}
+
+// adopted returns the value which the proposal has to adopt, or an empty state if none may have been chosen
+func (r *round) adopted() state {
+	if r.prior.id != domain.FastBallot {
+		return r.prior
+	}
+
+	unknown := r.acceptors - r.accepted - r.priors
+	adopted, most := state{}, 0
+	for val, votes := range r.fastVotes {
+		if votes+unknown >= r.fast && votes > most {
+			adopted, most = state{id: domain.FastBallot, val: val}, votes
+		}
+	}
+	return adopted
+}
+
+// free checks if a quorum of acceptors has promised the proposal and none of their values may have been chosen
+func (r *round) free() bool {
+	return r.recoverable() && r.adopted().id == 0
+}
+
+// done checks if the outcome of the round is known
+func (r *round) done() bool {
+	pending := r.acceptors - r.accepted - r.rejected - r.failed
+	if r.succeeded() || r.preempted || r.accepted+r.priors+pending < r.quorum {
+		return true
+	}
+	return r.recoverable() && r.accepted+pending < r.quorum
+}
+
+// reachable checks if a quorum of acceptors has responded
+func (r *round) reachable() bool {
+	return r.preempted || r.accepted+r.rejected >= r.quorum
+}
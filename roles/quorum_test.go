@@ -0,0 +1,152 @@
+package roles
+
+import (
+	"errors"
+	"github.com/go-paxos/config"
+	"github.com/go-paxos/domain"
+	"testing"
+)
+
+const pid = 20
+
+// promise is a promise of an acceptor which has neither promised nor accepted another proposal for the slot
+func promise(acceptor int) response {
+	return response{acceptor: acceptor, res: domain.Acceptance{From: acceptor, PID: pid}}
+}
+
+// promised is the response of an acceptor which has already promised the given proposal, and accepted the given prior
+// proposal unless its id is zero
+func promised(acceptor, id, priorID int, priorVal string) response {
+	res := prior(acceptor, priorID, priorVal)
+	res.res.PrvPromise.Exists = true
+	res.res.PrvPromise.ID = id
+	res.res.PrvAccept.Exists = priorID != 0
+	return res
+}
+
+// prior is a promise of an acceptor which has accepted the given proposal for the slot before
+func prior(acceptor, id int, val string) response {
+	res := promise(acceptor)
+	res.res.PrvAccept.Exists = true
+	res.res.PrvAccept.ID = id
+	res.res.PrvAccept.Val = val
+	return res
+}
+
+func accepted(acceptor int, ok bool) response {
+	return response{acceptor: acceptor, res: domain.Acceptance{From: acceptor, PID: pid, Accepted: ok}}
+}
+
+func stale(acceptor int) response {
+	return response{acceptor: acceptor, err: errStale}
+}
+
+func failed(acceptor int) response {
+	return response{acceptor: acceptor, err: errors.New(`connection refused`)}
+}
+
+func equal(votes ...int) map[int]int {
+	m := map[int]int{}
+	for i, v := range votes {
+		m[i+1] = v
+	}
+	return m
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		name        string
+		conf        config.Conf
+		typ         string
+		votes       map[int]int
+		responses   []response
+		done        bool
+		succeeded   bool
+		preempted   bool
+		reachable   bool
+		recoverable bool
+		adopted     state
+	}{
+		{name: `prepare pending`, typ: typePrepare, votes: equal(1, 1, 1), responses: []response{promise(1)}},
+		{name: `prepare majority`, typ: typePrepare, votes: equal(1, 1, 1), responses: []response{promise(1), promise(3)}, done: true, succeeded: true, reachable: true, recoverable: true},
+		{name: `prepare rejected`, typ: typePrepare, votes: equal(1, 1, 1), responses: []response{promise(1), stale(2), failed(3)},
+			done: true, reachable: true},
+		{name: `prepare unreachable`, typ: typePrepare, votes: equal(1, 1, 1), responses: []response{promise(1), failed(2), failed(3)}, done: true},
+		{name: `prepare preempted`, typ: typePrepare, votes: equal(1, 1, 1), responses: []response{promise(1), promised(2, 30, 0, ``)}, done: true, preempted: true, reachable: true},
+		{name: `prepare preempted by a promise after an accept`, typ: typePrepare, votes: equal(1, 1, 1),
+			responses: []response{promise(1), promised(2, 30, 10, `a`)}, done: true, preempted: true, reachable: true},
+		{name: `prepare turned down by accepts`, typ: typePrepare, votes: equal(1, 1, 1), responses: []response{prior(1, 10, `a`), prior(2, 15, `b`)},
+			done: true, reachable: true, recoverable: true, adopted: state{id: 15, val: `b`}},
+		{name: `prepare waits for the promise of a prior`, typ: typePrepare, votes: equal(1, 1, 1), responses: []response{prior(1, 10, `a`), promise(2)},
+			reachable: true, recoverable: true, adopted: state{id: 10, val: `a`}},
+		{name: `prepare of even votes`, typ: typePrepare, votes: equal(1, 1, 1, 1), responses: []response{promise(1), promise(2)}},
+		{name: `prepare majority of even votes`, typ: typePrepare, votes: equal(1, 1, 1, 1), responses: []response{promise(1), promise(2), promise(4)}, done: true, succeeded: true, reachable: true, recoverable: true},
+		{name: `prepare of a weighted leader`, typ: typePrepare, votes: equal(3, 1, 1), responses: []response{promise(1)}, done: true, succeeded: true, reachable: true, recoverable: true},
+		{name: `prepare without the weighted leader`, typ: typePrepare, votes: equal(3, 1, 1), responses: []response{promise(2), promise(3)}},
+		{name: `prepare rejected by the weighted leader`, typ: typePrepare, votes: equal(3, 1, 1), responses: []response{stale(1)},
+			done: true, reachable: true},
+		{name: `accept majority`, typ: typeAccept, votes: equal(1, 1, 1), responses: []response{accepted(1, true), accepted(2, true)}, done: true, succeeded: true, reachable: true},
+		{name: `accept pending`, typ: typeAccept, votes: equal(1, 1, 1), responses: []response{accepted(1, true), accepted(2, false)}, reachable: true},
+		{name: `accept rejected`, typ: typeAccept, votes: equal(1, 1, 1), responses: []response{accepted(1, true), accepted(2, false), failed(3)},
+			done: true, reachable: true},
+		{name: `phase-1 quorum boundary`, conf: config.Conf{Phase1Quorum: 4}, typ: typePrepare, votes: equal(1, 1, 1, 1, 1),
+			responses: []response{promise(1), promise(2), promise(3)}},
+		{name: `phase-1 quorum reached`, conf: config.Conf{Phase1Quorum: 4}, typ: typePrepare, votes: equal(1, 1, 1, 1, 1),
+			responses: []response{promise(1), promise(2), promise(3), promise(4)}, done: true, succeeded: true, reachable: true, recoverable: true},
+		{name: `phase-2 quorum boundary`, conf: config.Conf{Phase1Quorum: 4}, typ: typeAccept, votes: equal(1, 1, 1, 1, 1),
+			responses: []response{accepted(1, true), accepted(2, false), accepted(3, false), accepted(4, false), failed(5)}, done: true, reachable: true},
+		{name: `phase-2 quorum reached`, conf: config.Conf{Phase1Quorum: 4}, typ: typeAccept, votes: equal(1, 1, 1, 1, 1),
+			responses: []response{accepted(1, true), accepted(2, true)}, done: true, succeeded: true, reachable: true},
+		{name: `fast quorum boundary`, conf: config.Conf{FastPaxos: true}, typ: typeFast, votes: equal(1, 1, 1, 1, 1),
+			responses: []response{accepted(1, true), accepted(2, true), accepted(3, true)}},
+		{name: `fast quorum reached`, conf: config.Conf{FastPaxos: true}, typ: typeFast, votes: equal(1, 1, 1, 1, 1),
+			responses: []response{accepted(1, true), accepted(2, true), accepted(3, true), accepted(4, true)}, done: true, succeeded: true, reachable: true},
+		{name: `fast round collided`, conf: config.Conf{FastPaxos: true}, typ: typeFast, votes: equal(1, 1, 1, 1, 1),
+			responses: []response{accepted(1, true), accepted(2, false), accepted(3, false)}, done: true},
+		{name: `fast round of a weighted leader`, conf: config.Conf{FastPaxos: true}, typ: typeFast, votes: equal(3, 1, 1),
+			responses: []response{accepted(1, true), accepted(2, true)}, done: true, succeeded: true, reachable: true},
+		{name: `fast value may have been chosen`, conf: config.Conf{FastPaxos: true}, typ: typePrepare, votes: equal(1, 1, 1, 1, 1),
+			responses: []response{prior(1, domain.FastBallot, `a`), prior(2, domain.FastBallot, `a`), prior(3, domain.FastBallot, `b`)}, done: true, reachable: true, recoverable: true, adopted: state{id: domain.FastBallot, val: `a`}},
+		{name: `no fast value may have been chosen`, conf: config.Conf{FastPaxos: true}, typ: typePrepare, votes: equal(1, 1, 1, 1, 1),
+			responses: []response{prior(1, domain.FastBallot, `a`), prior(2, domain.FastBallot, `b`), promise(3), failed(4)}, done: true, reachable: true, recoverable: true},
+		{name: `classic prior after a fast round`, conf: config.Conf{FastPaxos: true}, typ: typePrepare, votes: equal(1, 1, 1, 1, 1),
+			responses: []response{prior(1, domain.FastBallot, `a`), prior(2, 10, `c`), promise(3), failed(4)}, done: true, reachable: true, recoverable: true, adopted: state{id: 10, val: `c`}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conf := test.conf
+			config.Set(&conf)
+			r := newRound(test.typ, test.votes)
+			for _, res := range test.responses {
+				r.add(res)
+			}
+
+			if done := r.done(); done != test.done {
+				t.Errorf(`expected done: %t, found: %t`, test.done, done)
+			}
+			if succeeded := r.succeeded(); succeeded != test.succeeded {
+				t.Errorf(`expected succeeded: %t, found: %t`, test.succeeded, succeeded)
+			}
+			if r.preempted != test.preempted {
+				t.Errorf(`expected preempted: %t, found: %t`, test.preempted, r.preempted)
+			}
+			if reachable := r.reachable(); reachable != test.reachable {
+				t.Errorf(`expected reachable: %t, found: %t`, test.reachable, reachable)
+			}
+			if test.typ != typePrepare {
+				return
+			}
+
+			if recoverable := r.recoverable(); recoverable != test.recoverable {
+				t.Errorf(`expected recoverable: %t, found: %t`, test.recoverable, recoverable)
+			}
+			if adopted := r.adopted(); adopted != test.adopted {
+				t.Errorf(`expected adopted: %+v, found: %+v`, test.adopted, adopted)
+			}
+			if free := r.free(); free != (test.recoverable && test.adopted.id == 0) {
+				t.Errorf(`expected free: %t, found: %t`, !free, free)
+			}
+		})
+	}
+}
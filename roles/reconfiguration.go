@@ -0,0 +1,191 @@
+package roles
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-paxos/config"
+	"github.com/go-paxos/domain"
+	"github.com/go-paxos/logger"
+	"github.com/go-paxos/tracing"
+	"net/http"
+)
+
+/* Leader functions */
+
+// Membership returns the configuration which decides the next slot of the leader along with the pending ones
+func (l *Leader) Membership() domain.Membership {
+	return l.members.Status(l.lastSlot() + 1)
+}
+
+/* Replica functions */
+
+// Reconfigure submits a reconfiguration to be decided once it is validated against the latest configuration
+func (r *Replica) Reconfigure(ctx context.Context, rc domain.Reconfiguration) error {
+	if r.IsLearner() {
+		return logger.ErrorWithLine(errors.New(errLearner))
+	}
+
+	next, err := r.members.Latest().Nodes.Apply(rc)
+	if err != nil {
+		return logger.ErrorWithLine(err)
+	}
+
+	// the configured quorums should still intersect among the acceptors of the resulting configuration
+	conf := config.Get()
+	if err = domain.ValidateQuorums(next.Votes(), conf.Phase1Quorum, conf.Phase2Quorum); err != nil {
+		return logger.ErrorWithLine(err)
+	}
+	if err = conf.ValidateFastQuorum(next.Votes()); err != nil {
+		return logger.ErrorWithLine(err)
+	}
+
+	_, err = r.request(ctx, rc.Value())
+	return err
+}
+
+// Membership returns the configuration which decides the next slot of the replica along with the pending ones
+func (r *Replica) Membership() domain.Membership {
+	r.lock.Lock()
+	next := len(r.log)
+	r.lock.Unlock()
+	return r.members.Status(next)
+}
+
+// CatchUp fetches the entries missing in the log from the other replicas and learners
+func (r *Replica) CatchUp(ctx context.Context) error {
+	r.lock.Lock()
+	from := len(r.log)
+	r.lock.Unlock()
+	defer func() {
+		r.lock.Lock()
+		r.catchingUp = false
+		r.lock.Unlock()
+	}()
+
+	var lastErr error
+	caughtUp := false
+	peers := append(r.members.IDs(from, domain.RoleReplica, r.id), r.members.IDs(from, domain.RoleLearner, r.id)...)
+	for _, peer := range peers {
+		var entries []domain.Entry
+		err := r.fetch(ctx, peer, fmt.Sprintf(`%s?from=%d`, domain.LogReplicaEndpoint, from), &entries)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if err = r.applyEntries(ctx, peer, entries); err != nil {
+			return logger.ErrorWithLine(err)
+		}
+
+		r.logger.InfoContext(ctx, fmt.Sprintf(`caught up with node %d (entries: %d)`, peer, len(entries)))
+		caughtUp = true
+		break
+	}
+
+	if caughtUp && !r.hasGaps() {
+		return nil
+	}
+
+	for _, leader := range r.members.IDs(from, domain.RoleLeader, r.id) {
+		count, err := r.catchUpWithLeader(ctx, leader)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		r.logger.InfoContext(ctx, fmt.Sprintf(`caught up with the decisions of leader %d (entries: %d)`, leader, count))
+		return nil
+	}
+
+	if lastErr != nil {
+		return logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s: %s`, errCatchUp, lastErr)))
+	}
+
+	return nil
+}
+
+// catchUpWithLeader applies the decisions retained by a single leader in batches
+func (r *Replica) catchUpWithLeader(ctx context.Context, leader int) (int, error) {
+	count := 0
+	for {
+		r.lock.Lock()
+		from := len(r.log)
+		r.lock.Unlock()
+
+		var dl domain.DecisionLog
+		err := r.fetch(ctx, leader, fmt.Sprintf(`%s?from=%d`, domain.DecisionsEndpoint, from), &dl)
+		if err != nil {
+			return count, logger.ErrorWithLine(err)
+		}
+
+		if err = r.applyEntries(ctx, leader, dl.Entries); err != nil {
+			return count, logger.ErrorWithLine(err)
+		}
+		count += len(dl.Entries)
+
+		// the gap can not be filled by a leader which no longer retains the decision of the next slot
+		if dl.Floor >= from {
+			return count, logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (leader: %d, slot: %d, floor: %d)`, errDiscarded, leader, from, dl.Floor)))
+		}
+
+		if len(dl.Entries) < decisionLogLimit || !r.applied(from) {
+			return count, nil
+		}
+	}
+}
+
+// applyEntries updates the log with the entries fetched from the given node
+func (r *Replica) applyEntries(ctx context.Context, node int, entries []domain.Entry) error {
+	for _, entry := range entries {
+		if r.applied(entry.SlotID) {
+			continue
+		}
+
+		err := r.Update(ctx, domain.Decision{From: node, SlotID: entry.SlotID, Val: entry.Value()})
+		if err != nil {
+			return logger.ErrorWithLine(err)
+		}
+	}
+
+	return nil
+}
+
+// hasGaps checks if there are decisions received for future slots which can not be applied yet
+func (r *Replica) hasGaps() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return len(r.pendingLog) > 0
+}
+
+// applied checks if the slot has already been applied to the log
+func (r *Replica) applied(slot int) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return slot < len(r.log)
+}
+
+// fetch decodes the response of a GET request sent to the given node
+func (r *Replica) fetch(ctx context.Context, node int, endpoint string, v interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, config.Get().ReplicaTimeout.Duration)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, `http://`+r.book.Address(node)+endpoint, nil)
+	if err != nil {
+		return logger.ErrorWithLine(err)
+	}
+	tracing.Inject(ctx, req)
+	domain.InjectTimeout(ctx, req)
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return logger.ErrorWithLine(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (node: %d, status: %d)`, errCatchUp, node, res.StatusCode)))
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
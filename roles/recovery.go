@@ -0,0 +1,328 @@
+package roles
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-paxos/config"
+	"github.com/go-paxos/domain"
+	"github.com/go-paxos/logger"
+	"github.com/go-paxos/metrics"
+	"github.com/go-paxos/tracing"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Recover restores the state of a started leader from the acceptors and retries until a quorum of them responds
+func (l *Leader) Recover(ctx context.Context) {
+	for attempt := 0; ; attempt++ {
+		err := l.recover(ctx)
+		if err == nil {
+			close(l.recovery)
+			return
+		}
+
+		if l.snapshot().closed {
+			return
+		}
+
+		wait := backoff(recoveryBackoffMin, recoveryBackoffMax, attempt)
+		l.logger.WarnContext(ctx, fmt.Sprintf(`recovering leader state failed, retrying in %s: %s`, wait, err))
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Recovered checks if the leader has recovered its state from the acceptors
+func (l *Leader) Recovered() bool {
+	select {
+	case <-l.recovery:
+		return true
+	default:
+		return false
+	}
+}
+
+// recover restores the decided slots, the ballot and the undecided proposals of the leader from the acceptors
+func (l *Leader) recover(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, `leader.recover`)
+	defer span.End()
+
+	from := l.applied(ctx)
+	states, err := l.collect(ctx, from)
+	if err != nil {
+		span.SetError(err)
+		return logger.ErrorWithLine(err)
+	}
+
+	accepted := map[int]bool{}
+	decided := map[int]string{}
+	var configs []domain.Configuration
+	// the slots below the first one which has not been applied by all replicas are decided, even if the acceptors
+	// have lost them on a restart
+	lastSlot, decidedUpTo, ballot := from-1, from-1, 0
+	for _, st := range states {
+		if st.LastSlot > lastSlot {
+			lastSlot = st.LastSlot
+		}
+		if st.DecidedUpTo > decidedUpTo {
+			decidedUpTo = st.DecidedUpTo
+		}
+		if st.Ballot > ballot {
+			ballot = st.Ballot
+		}
+		for _, acc := range st.Accepted {
+			accepted[acc.Slot] = true
+			if acc.Slot > lastSlot {
+				lastSlot = acc.Slot
+			}
+		}
+		for _, entry := range st.Decided {
+			decided[entry.SlotID] = entry.Val
+		}
+		configs = append(configs, st.Configurations...)
+	}
+
+	var entries []domain.Entry
+	var free, undecided []int
+	for slot := from; slot <= lastSlot; slot++ {
+		if val, ok := decided[slot]; ok {
+			entries = append(entries, domain.Entry{SlotID: slot, Val: val})
+			continue
+		}
+		if slot <= decidedUpTo {
+			continue
+		}
+		if !accepted[slot] {
+			free = append(free, slot)
+			continue
+		}
+		undecided = append(undecided, slot)
+	}
+	l.learned(entries, decidedUpTo, configs)
+	l.recovered(lastSlot+1, ballot, free)
+
+	for _, entry := range entries {
+		l.disseminate(ctx, domain.Decision{From: l.id, SlotID: entry.SlotID, Val: entry.Val}, 0)
+	}
+
+	for _, slot := range undecided {
+		if err = l.recoverSlot(ctx, slot); err != nil {
+			span.SetError(err)
+			return logger.ErrorWithLine(err)
+		}
+	}
+
+	l.logger.InfoContext(ctx, fmt.Sprintf(`leader recovered from %d acceptors (last slot: %d, decided up to: %d, ballot: %d, free slots: %d, delivered again: %d, proposed again: %d)`,
+		len(states), lastSlot, decidedUpTo, ballot, len(free), len(entries), len(undecided)))
+	return nil
+}
+
+// recoverSlot proposes the slot again with the value which may have been decided for it, or gives it back if none
+func (l *Leader) recoverSlot(ctx context.Context, slot int) error {
+	prop, promises, err := l.prepareRound(ctx, slot, domain.Request{})
+	if err != nil {
+		return logger.ErrorWithLine(err)
+	}
+
+	if !promises.recoverable() {
+		l.logger.DebugContext(ctx, fmt.Sprintf(`recovery of slot %d was preempted by %d`, slot, promises.ballot))
+		metrics.RecoveredSlots.Inc(metrics.LabelNotChosen)
+		return nil
+	}
+
+	adopted := promises.adopted()
+	if adopted.id == 0 {
+		l.unassignSlot(slot)
+		return nil
+	}
+
+	prop.Val = adopted.val
+	_, ok, err := l.decide(ctx, prop, 0)
+	if err != nil {
+		return logger.ErrorWithLine(err)
+	}
+
+	if !ok {
+		metrics.RecoveredSlots.Inc(metrics.LabelNotChosen)
+		return nil
+	}
+	metrics.RecoveredSlots.Inc(metrics.LabelChosen)
+	return nil
+}
+
+// fillGaps catches up with the other acceptors and recovers the undecided slots up to the given one
+func (l *Leader) fillGaps(ctx context.Context, upTo int) error {
+	l.catchUp(ctx)
+
+	from := l.snapshot().decidedUpTo + 1
+	decided := map[int]bool{}
+	for _, entry := range l.DecisionLog(from).Entries {
+		decided[entry.SlotID] = true
+	}
+
+	for slot := from; slot <= upTo; slot++ {
+		if decided[slot] {
+			continue
+		}
+		if err := l.recoverSlot(ctx, slot); err != nil {
+			return logger.ErrorWithLine(err)
+		}
+	}
+
+	return nil
+}
+
+// collect requests the state of the acceptors from the given slot and fails unless a quorum of them responds
+func (l *Leader) collect(ctx context.Context, from int) ([]domain.AcceptorState, error) {
+	var acceptors []int
+	votes, total := map[int]int{}, 0
+	for _, node := range l.members.Latest().Nodes {
+		if node.Role == domain.RoleLeader {
+			acceptors = append(acceptors, node.ID)
+			votes[node.ID] = node.Votes()
+			total += node.Votes()
+		}
+	}
+	// the state of a prepare quorum contains every value which may have been chosen by an accept quorum
+	quorum, _, _ := quorums(total)
+
+	ctx, cancel := context.WithTimeout(ctx, config.Get().PrepareTimeout.Duration)
+	defer cancel()
+
+	type result struct {
+		acceptor int
+		st       domain.AcceptorState
+		err      error
+	}
+	results := make(chan result, len(acceptors))
+	for _, acceptor := range acceptors {
+		go func(acceptor int) {
+			st, err := l.requestState(ctx, acceptor, from)
+			results <- result{acceptor: acceptor, st: st, err: err}
+		}(acceptor)
+	}
+
+	var states []domain.AcceptorState
+	responded := 0
+	for range acceptors {
+		res := <-results
+		if res.err != nil {
+			l.logger.DebugContext(ctx, res.err)
+			continue
+		}
+		states = append(states, res.st)
+		responded += votes[res.acceptor]
+	}
+
+	if responded < quorum {
+		return nil, logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (responded votes: %d, quorum: %d)`, errNoQuorum, responded, quorum)))
+	}
+
+	return states, nil
+}
+
+// applied returns the length of the shortest log among the replicas and learners which respond, or zero
+func (l *Leader) applied(ctx context.Context) int {
+	var nodes []domain.Node
+	for _, node := range l.members.Latest().Nodes {
+		if node.Role == domain.RoleReplica || node.Role == domain.RoleLearner {
+			nodes = append(nodes, node)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, config.Get().ProbeTimeout.Duration)
+	defer cancel()
+
+	lengths := make(chan int, len(nodes))
+	for _, node := range nodes {
+		go func(node domain.Node) {
+			st, err := l.requestStatus(ctx, node.ID)
+			if err != nil {
+				l.logger.DebugContext(ctx, err)
+			}
+			if err != nil || st.Replica == nil {
+				lengths <- -1
+				return
+			}
+			lengths <- st.Replica.LogLength
+		}(node)
+	}
+
+	applied := -1
+	for range nodes {
+		if length := <-lengths; length >= 0 && (applied < 0 || length < applied) {
+			applied = length
+		}
+	}
+
+	if applied < 0 {
+		return 0
+	}
+	return applied
+}
+
+// requestState requests the state of a single acceptor from the given slot
+func (l *Leader) requestState(ctx context.Context, acceptor, from int) (domain.AcceptorState, error) {
+	if acceptor == l.id {
+		return l.AcceptorState(from), nil
+	}
+
+	var st domain.AcceptorState
+	err := l.get(ctx, acceptor, domain.AcceptorStateEndpoint+`?from=`+strconv.Itoa(from), &st)
+	return st, err
+}
+
+// requestStatus requests the status of a single node
+func (l *Leader) requestStatus(ctx context.Context, id int) (domain.Status, error) {
+	var st domain.Status
+	err := l.get(ctx, id, domain.StatusEndpoint, &st)
+	return st, err
+}
+
+// get requests the given endpoint of a node and decodes the response into v
+func (l *Leader) get(ctx context.Context, id int, endpoint string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, `http://`+l.book.Address(id)+endpoint, nil)
+	if err != nil {
+		return errors.New(fmt.Sprintf(`%s for node: %d`, err.Error(), id))
+	}
+	tracing.Inject(ctx, req)
+	domain.InjectTimeout(ctx, req)
+
+	res, err := l.client.Do(req)
+	if err != nil {
+		return errors.New(fmt.Sprintf(`%s for node: %d`, err.Error(), id))
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return errors.New(fmt.Sprintf(`%s (endpoint: %s, status: %d) for node: %d`, errRequestNode, endpoint, res.StatusCode, id))
+	}
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return errors.New(fmt.Sprintf(`%s for node: %d`, err.Error(), id))
+	}
+
+	err = json.Unmarshal(data, v)
+	if err != nil {
+		return errors.New(fmt.Sprintf(`%s for node: %d`, err.Error(), id))
+	}
+
+	return nil
+}
+
+/* Acceptor functions */
+
+// AcceptorState returns the proposals accepted by the acceptor and the values it knows to be decided from the given slot
+func (l *Leader) AcceptorState(from int) domain.AcceptorState {
+	reply := make(chan domain.AcceptorState, 1)
+	l.events <- acceptorStateMsg{from: from, reply: reply}
+	return <-reply
+}
@@ -6,141 +6,198 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/go-paxos/config"
 	"github.com/go-paxos/domain"
 	"github.com/go-paxos/logger"
+	"github.com/go-paxos/metrics"
+	"github.com/go-paxos/tracing"
 	"github.com/tryfix/log"
 	"io/ioutil"
 	"net/http"
 	"sync"
-	"time"
 )
 
+// Replica keeps the log of decisions and forwards client requests to leaders
 type Replica struct {
-	hostname   string
+	id         int
+	role       string
 	log        []string
+	keys       map[string]int // slots of the values decided with idempotency keys
 	pendingLog map[int]string
-	leaders    []string
-	proceed    chan bool
+	reserved   map[int]bool  // slots of the fast rounds in progress
+	changed    chan struct{} // closed and renewed whenever an entry is applied to the log
+	leaders    []int         // in the order of preference
+	members    *Membership
+	book       *AddressBook
+	catchingUp bool
+	closed     bool
 	client     *http.Client
 	lock       *sync.Mutex
 	logger     log.Logger
 }
 
-func NewReplica(hostname string, leaders []string, logger log.Logger) *Replica {
-	proceedChan := make(chan bool)
+func NewReplica(id int, members *Membership, book *AddressBook, logger log.Logger) *Replica {
 	r := &Replica{
-		hostname:   hostname,
-		leaders:    leaders,
+		id:         id,
+		role:       domain.RoleReplica,
+		leaders:    members.IDs(0, domain.RoleLeader, id),
+		members:    members,
+		book:       book,
+		keys:       map[string]int{},
 		pendingLog: map[int]string{},
-		proceed:    proceedChan,
-		client:     &http.Client{Timeout: time.Duration(domain.Config.ReplicaTimeout) * time.Second},
+		reserved:   map[int]bool{},
+		changed:    make(chan struct{}),
+		client:     &http.Client{},
 		lock:       &sync.Mutex{},
 		logger:     logger,
 	}
-	go func() { r.proceed <- true }()
+	return r
+}
 
+// NewLearner creates a replica which only keeps the log and never forwards client requests
+func NewLearner(id int, members *Membership, book *AddressBook, logger log.Logger) *Replica {
+	r := NewReplica(id, members, book, logger)
+	r.role = domain.RoleLearner
+	r.leaders = nil
 	return r
 }
 
-// HandleRequest builds a request from the client value and forwards the request received to a leader
-func (r *Replica) HandleRequest(ctx context.Context, val string) error {
-	// go routine waits until the pending request by this replica is done (if there's any)
-	<-r.proceed
+// IsLearner checks if the replica only learns decisions without forwarding client requests
+func (r *Replica) IsLearner() bool {
+	return r.role == domain.RoleLearner
+}
 
-	req := r.buildRequest(val)
-	for {
-		dec, errRes, ok, err := r.send(req)
-		if err != nil {
-			if err.Error() == errFutureSlot {
-				req.SlotID = errRes.LastSlot + 1
-				continue
-			}
-			go func() { r.proceed <- true }()
-			return logger.ErrorWithLine(err)
-		}
+// HandleRequest forwards the client value to a leader, or proposes it directly when fast rounds are enabled
+func (r *Replica) HandleRequest(ctx context.Context, key, val string) (domain.Result, error) {
+	if r.IsLearner() {
+		return domain.Result{}, logger.ErrorWithLine(errors.New(errLearner))
+	}
 
-		if ok {
-			go func() { r.proceed <- true }()
-			err = r.Update(ctx, dec)
-			if err != nil {
-				return logger.ErrorWithLine(err)
-			}
-			return nil
-		}
+	if domain.IsReserved(val) {
+		return domain.Result{}, logger.ErrorWithLine(errors.New(errReservedValue))
+	}
+
+	if slot, ok := r.decided(key); ok {
+		return domain.Result{SlotID: slot, Val: val, Duplicate: true}, nil
+	}
 
-		req.SlotID++
+	var dec domain.Decision
+	var fast bool
+	var err error
+	if config.Get().FastPaxos {
+		dec, fast, err = r.requestFast(ctx, domain.KeyedValue(key, val))
+	} else {
+		dec, err = r.request(ctx, domain.KeyedValue(key, val))
 	}
+	if err != nil {
+		return domain.Result{}, logger.ErrorWithLine(err)
+	}
+
+	if slot, ok := r.decided(key); ok && slot != dec.SlotID {
+		return domain.Result{SlotID: slot, Val: val, Duplicate: true}, nil
+	}
+
+	return domain.Result{SlotID: dec.SlotID, Val: val, Fast: fast}, nil
 }
 
-// buildRequest builds the request from the client value
-func (r *Replica) buildRequest(val string) domain.Request {
+// decided returns the slot of the value decided with the given idempotency key
+func (r *Replica) decided(key string) (int, bool) {
+	if key == `` {
+		return 0, false
+	}
+
 	r.lock.Lock()
 	defer r.lock.Unlock()
+	slot, ok := r.keys[key]
+	return slot, ok
+}
+
+// request forwards the value to a leader, which decides it for a slot of its choice, and applies the decision
+func (r *Replica) request(ctx context.Context, val string) (domain.Decision, error) {
+	if r.isClosed() {
+		return domain.Decision{}, logger.ErrorWithLine(errors.New(errClosed))
+	}
 
-	// check if the pending log has already received the slot value
-	slotId := len(r.log)
-tryPendingLog:
-	pendingVal, ok := r.pendingLog[slotId]
-	if ok {
-		r.log = append(r.log, pendingVal)
-		delete(r.pendingLog, slotId)
-		slotId++
-		goto tryPendingLog
+	dec, err := r.send(ctx, domain.Request{From: r.id, Val: val})
+	if err != nil {
+		return domain.Decision{}, logger.ErrorWithLine(err)
 	}
 
-	return domain.Request{
-		Replica: r.hostname,
-		SlotID:  slotId,
-		Val:     val,
+	err = r.Update(ctx, dec)
+	if err != nil {
+		return domain.Decision{}, logger.ErrorWithLine(err)
 	}
+
+	return dec, nil
 }
 
-// Sends the request to first leader found in the leader list. If the list is empty, an error is returned with success as false
-func (r *Replica) send(replicaReq domain.Request) (dec domain.Decision, errRes domain.ErrorRes, ok bool, err error) {
-	if len(r.leaders) == 0 {
-		return domain.Decision{}, domain.ErrorRes{}, false, logger.ErrorWithLine(errors.New(errNoLeader))
+// Sends the request to the first leader in the order of preference which is a member of the next configuration
+func (r *Replica) send(ctx context.Context, replicaReq domain.Request) (dec domain.Decision, err error) {
+	ctx, span := tracing.Start(ctx, `replica.send`)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
+	r.lock.Lock()
+	leaders := r.leaders
+	next := len(r.log)
+	r.lock.Unlock()
+
+	leader := 0
+	for _, l := range leaders {
+		if r.members.IsMember(next, l) {
+			leader = l
+			break
+		}
 	}
 
+	if leader == 0 {
+		return domain.Decision{}, logger.ErrorWithLine(errors.New(errNoLeader))
+	}
+	span.SetAttribute(`leader`, leader)
+
 	data, err := json.Marshal(replicaReq)
 	if err != nil {
-		return domain.Decision{}, domain.ErrorRes{}, false, logger.ErrorWithLine(err)
+		return domain.Decision{}, logger.ErrorWithLine(err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, `http://`+r.leaders[0]+domain.RequestLeaderEndpoint, bytes.NewBuffer(data))
+	ctx, cancel := context.WithTimeout(ctx, config.Get().ReplicaTimeout.Duration)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, `http://`+r.book.Address(leader)+domain.RequestLeaderEndpoint, bytes.NewBuffer(data))
 	if err != nil {
-		return domain.Decision{}, domain.ErrorRes{}, false, logger.ErrorWithLine(err)
+		return domain.Decision{}, logger.ErrorWithLine(err)
 	}
+	tracing.Inject(ctx, req)
+	domain.InjectTimeout(ctx, req)
 
 	res, err := r.client.Do(req)
 	if err != nil {
-		return domain.Decision{}, domain.ErrorRes{}, false, logger.ErrorWithLine(err)
+		return domain.Decision{}, logger.ErrorWithLine(err)
 	}
 	defer res.Body.Close()
 
 	resData, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return domain.Decision{}, domain.ErrorRes{}, false, logger.ErrorWithLine(err)
+		return domain.Decision{}, logger.ErrorWithLine(err)
 	}
 
-	// too early status implies that replica requested for a future slot
-	if res.StatusCode == http.StatusTooEarly {
-		err = json.Unmarshal(resData, &errRes)
-		if err != nil {
-			return domain.Decision{}, domain.ErrorRes{}, false, logger.ErrorWithLine(err)
-		}
-		return domain.Decision{}, errRes, false, errors.New(errFutureSlot)
+	// the leader runs out of the time given by the replica just before the replica does, and the request is then
+	// reported as timed out rather than failed
+	if res.StatusCode == http.StatusGatewayTimeout {
+		return domain.Decision{}, logger.ErrorWithLine(fmt.Errorf(`%s (leader: %d, status: %d): %w`, errRequestLeader, leader, res.StatusCode, context.DeadlineExceeded))
 	}
 
 	if res.StatusCode != http.StatusOK {
-		return domain.Decision{}, domain.ErrorRes{}, false, nil
+		return domain.Decision{}, logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (leader: %d, status: %d)`, errRequestLeader, leader, res.StatusCode)))
 	}
 
 	err = json.Unmarshal(resData, &dec)
 	if err != nil {
-		return domain.Decision{}, domain.ErrorRes{}, false, logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s for value %s (res: %s)`, err.Error(), replicaReq.Val, string(resData))))
+		return domain.Decision{}, logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s for value %s (res: %s)`, err.Error(), replicaReq.Val, string(resData))))
 	}
 
-	return dec, domain.ErrorRes{}, res.StatusCode == http.StatusOK, nil
+	return dec, nil
 }
 
 // Update updates the log of the current replica when a decision is made by the leaders
@@ -157,6 +214,24 @@ func (r *Replica) Update(ctx context.Context, dec domain.Decision) error {
 			}
 		}
 		r.pendingLog[dec.SlotID] = dec.Val
+		r.updateMetrics()
+
+		// fills the gap from the other replicas in case the missing decisions were not sent to this replica
+		if !r.catchingUp {
+			r.catchingUp = true
+			// the catch-up outlives the request which delivered the decision
+			catchUpCtx := tracing.Detach(ctx)
+			go func() {
+				if err := r.CatchUp(catchUpCtx); err != nil {
+					r.logger.WarnContext(catchUpCtx, err)
+				}
+			}()
+		}
+		return nil
+	}
+
+	// a decision which is delivered again (eg: after the response of a delivery was lost) has already been applied
+	if dec.SlotID < len(r.log) && r.log[dec.SlotID] == dec.Val {
 		return nil
 	}
 
@@ -164,8 +239,161 @@ func (r *Replica) Update(ctx context.Context, dec domain.Decision) error {
 		return logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (slot: %d, log size: %d)`, errInvalidDecision, dec.SlotID, len(r.log))))
 	}
 
-	r.log = append(r.log, dec.Val)
+	r.apply(ctx, dec.Val)
+	r.applyPending(ctx)
+	r.updateMetrics()
 	r.logger.DebugContext(ctx, `replica state updated`, r.log)
 
 	return nil
 }
+
+// apply appends a decided value to the log and applies the membership change if the value is a reconfiguration
+func (r *Replica) apply(ctx context.Context, val string) {
+	slot := len(r.log)
+	r.log = append(r.log, val)
+	close(r.changed)
+	r.changed = make(chan struct{})
+
+	// only the first value decided with a key is applied, the later ones are kept in the log as duplicates
+	if key, _ := domain.ParseKeyedValue(val); key != `` {
+		if first, ok := r.keys[key]; ok {
+			metrics.DuplicateKeys.Inc()
+			r.logger.DebugContext(ctx, fmt.Sprintf(`duplicate of slot %d not applied (slot: %d)`, first, slot))
+			return
+		}
+		r.keys[key] = slot
+	}
+
+	rc, applied, err := r.members.Apply(slot, val)
+	if err != nil {
+		r.logger.ErrorContext(ctx, err)
+		return
+	}
+
+	if !applied {
+		return
+	}
+
+	if rc.Op == domain.ReconfigAdd && rc.Node.Role == domain.RoleLeader && !r.IsLearner() {
+		r.leaders = append(r.leaders, rc.Node.ID)
+	}
+	r.logger.InfoContext(ctx, fmt.Sprintf(`membership reconfigured (op: %s, node: %d, decided slot: %d, effective slot: %d)`,
+		rc.Op, rc.Node.ID, slot, r.members.Latest().Slot))
+}
+
+// applyPending applies the decisions in the pending log which follow the last applied slot
+func (r *Replica) applyPending(ctx context.Context) {
+	for {
+		val, ok := r.pendingLog[len(r.log)]
+		if !ok {
+			return
+		}
+		delete(r.pendingLog, len(r.log))
+		r.apply(ctx, val)
+	}
+}
+
+// updateMetrics exposes the current log sizes of the replica and should be called while holding the lock
+func (r *Replica) updateMetrics() {
+	label := metrics.LabelReplica
+	if r.IsLearner() {
+		label = metrics.LabelLearner
+	}
+	metrics.SlotIndex.Set(float64(len(r.log)-1), label)
+	metrics.PendingLogSize.Set(float64(len(r.pendingLog)))
+}
+
+// Log returns the applied entries of the replica log within the range [from, to)
+func (r *Replica) Log(from, to int) []domain.Entry {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.entries(from, to)
+}
+
+// Entries returns up to max applied entries from the given slot and blocks until the slot is applied
+func (r *Replica) Entries(ctx context.Context, from, max int) ([]domain.Entry, error) {
+	for {
+		r.lock.Lock()
+		if from < len(r.log) {
+			entries := r.entries(from, from+max)
+			r.lock.Unlock()
+			return entries, nil
+		}
+		changed := r.changed
+		r.lock.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// WaitFor blocks until the given slot is applied to the log and returns an error if the context is done before that
+func (r *Replica) WaitFor(ctx context.Context, slot int) error {
+	if _, err := r.Entries(ctx, slot, 1); err != nil {
+		return logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (slot: %d, applied: %d)`, errNotApplied, slot, r.Applied())))
+	}
+
+	return nil
+}
+
+// Applied returns the last slot applied to the log
+func (r *Replica) Applied() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return len(r.log) - 1
+}
+
+// entries returns the applied entries within the range [from, to) and should be called while holding the lock
+func (r *Replica) entries(from, to int) []domain.Entry {
+	if to < 0 || to > len(r.log) {
+		to = len(r.log)
+	}
+
+	entries := []domain.Entry{}
+	for slot := from; slot < to; slot++ {
+		if slot < 0 {
+			continue
+		}
+		key, val := domain.ParseKeyedValue(r.log[slot])
+		dup := key != `` && r.keys[key] != slot
+		entries = append(entries, domain.Entry{SlotID: slot, Key: key, Val: val, Duplicate: dup})
+	}
+
+	return entries
+}
+
+// PreferLeader moves the given leader to the front of the leader list so that subsequent requests are forwarded to it
+func (r *Replica) PreferLeader(leader int) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for i, l := range r.leaders {
+		if l != leader {
+			continue
+		}
+
+		leaders := []int{leader}
+		leaders = append(leaders, r.leaders[:i]...)
+		r.leaders = append(leaders, r.leaders[i+1:]...)
+		return nil
+	}
+
+	return logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (leader: %d)`, errUnknownLeader, leader)))
+}
+
+// Close stops the replica from forwarding further requests once the in-flight requests are completed
+func (r *Replica) Close(ctx context.Context) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.closed = true
+	r.logger.InfoContext(ctx, fmt.Sprintf(`%s closed (log length: %d, pending decisions: %d)`, r.role, len(r.log), len(r.pendingLog)))
+}
+
+func (r *Replica) isClosed() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.closed
+}
@@ -2,105 +2,39 @@
 num_leaders=$1
 num_replicas=$2
 first_port=$3
+num_learners=${4:-0}
 
-if [ ! -f ./leaders.txt ]
-  then
-    :
-  else
-    rm leaders.txt
-fi
+rm -f leaders.txt replicas.txt learners.txt
 
-if [ ! -f ./replicas.txt ]
-  then
-    :
-  else
-    rm replicas.txt
-fi
+# generating the cluster topology on top of the configurations in the parent directory
+config="cluster.yaml"
+sed '/^# cluster topology/,$d' ../configs.yaml > $config
+echo "nodes:" >> $config
 
-# generating ports and leaders
-leaders=()
-for i in `seq 0 $((num_leaders-1))`
+num_nodes=$((num_leaders+num_replicas+num_learners))
+id=1
+for i in $(seq 0 $((num_nodes-1)))
 do
-  # shellcheck disable=SC2100
-  p=$((first_port+i))
-  leader="localhost:${p}"
-  leaders+=("${leader}")
-  echo "$leader" >> leaders.txt
-done
-
-# generating ports and replicas
-replicas=()
-first_port=$first_port+$num_leaders
-for i in $(seq 0 $((num_replicas-1)))
-do
-  # shellcheck disable=SC2100
-  p=$((first_port+i))
-  replica="localhost:${p}"
-  replicas+=("$replica")
-  echo "$replica" >> replicas.txt
-done
-
-# creating replica list as a string
-replicas_str=""
-for r in "${replicas[@]}"; do
-  if [ "$replicas_str" = "" ]; then
-    replicas_str+=" ${r}"
+  address="localhost:$((first_port+i))"
+  if [ "$i" -lt "$num_leaders" ]; then
+    role="leader"
+    echo "$address" >> leaders.txt
+  elif [ "$i" -lt "$((num_leaders+num_replicas))" ]; then
+    role="replica"
+    echo "$address" >> replicas.txt
   else
-    replicas_str+=",${r}"
+    role="learner"
+    echo "$address" >> learners.txt
   fi
-done
 
-# creating leader list as a string
-leaders_str=""
-for l in "${leaders[@]}"; do
-  if [ "$leaders_str" = "" ]; then
-    leaders_str+="${l}"
-  else
-    leaders_str+=",${l}"
-  fi
+  printf "  - id: %d\n    role: %s\n    address: %s\n" $id $role "$address" >> $config
+  id=$((id+1))
 done
 
 cd ..
 
-# starting leaders
-for l in "${leaders[@]}"; do
-  index=0
-  command="./run leader ${l} "
-  for a in "${leaders[@]}"; do
-      # shellcheck disable=SC2077
-      if [ "$a" = "$l" ]
-      then
-        continue
-      else
-        index=$((index+1))
-        if [[ $index != 1 ]]; then
-          command+=","
-        fi
-        command+=$a
-      fi
-  done
-  command+=$replicas_str
-#  echo "$command" >> leaders.txt
-  eval "$command &"
-done
-
-# starting replicas
-for r in "${replicas[@]}"; do
-  index=0
-  command="./run replica ${r} ${leaders_str} "
-  for a in "${replicas[@]}"; do
-      # shellcheck disable=SC2077
-      if [ "$a" = "$r" ]
-      then
-        continue
-      else
-        index=$((index+1))
-        if [[ $index != 1 ]]; then
-          command+=","
-        fi
-        command+=$a
-      fi
-  done
-  eval "$command &"
-#  echo "$command" >> replicas.txt
+# starting all the nodes (leaders first)
+for id in $(seq 1 $num_nodes)
+do
+  ./run --config "scripts/${config}" --id "$id" --data-dir "data/$id" &
 done
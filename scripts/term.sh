@@ -1,12 +1,17 @@
 #!/bin/bash
 
-for node in $(cat leaders.txt); do
-  echo "${node}/internal/terminate"
-  curl -X POST --retry 3 "http://${node}/internal/terminate"
-done
-rm leaders.txt
+# paxosctl should be built in the parent directory using `go build -o paxosctl ./cmd/paxosctl`
+leaders=$(paste -sd, leaders.txt)
+replicas=$(paste -sd, replicas.txt)
+
+# replicas are drained first so that no new proposals reach the leaders
+../paxosctl -nodes "${replicas}" drain -terminate
+rm replicas.txt
 
-for node in $(cat replicas.txt); do
-  curl -X POST --retry 3 "http://${node}/internal/terminate"
-done
-rm replicas.txt
\ No newline at end of file
+if [ -f learners.txt ]; then
+  ../paxosctl -nodes "$(paste -sd, learners.txt)" drain -terminate
+  rm learners.txt
+fi
+
+../paxosctl -nodes "${leaders}" drain -terminate
+rm leaders.txt
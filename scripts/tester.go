@@ -1,13 +1,13 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/csv"
 	"fmt"
+	"github.com/go-paxos/client"
 	"io"
 	"log"
 	"math/rand"
-	"net/http"
 	"os"
 	"strconv"
 	"strings"
@@ -16,14 +16,10 @@ import (
 	"time"
 )
 
-var (
-	httpClient = &http.Client{Timeout: 120 * time.Second}
-)
-
 func main() {
 	args := os.Args
 	if len(args) != 4 {
-		log.Fatalln(`command should be in the form of ./<tester> <upper threshold of concurrent clients> <number of requests> <replica list>`)
+		log.Fatalln(`command should be in the form of ./<tester> <upper threshold of concurrent clients> <number of requests> <seed node list>`)
 	}
 
 	numClients, err := strconv.Atoi(args[1])
@@ -36,57 +32,74 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	replicas := hosts(args[3])
+	ctx := context.Background()
+	c, err := client.New(ctx, client.Config{Seeds: hosts(args[3]), Timeout: 120 * time.Second, MaxIdleConns: numClients})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer c.Close()
+	fmt.Printf("discovered replicas: %s\n", strings.Join(c.Replicas(), `, `))
+
 	wg := &sync.WaitGroup{}
-	var counter uint64
+	st := &stats{}
 	startTime := time.Now().UTC()
 	for i := 0; i < numClients; i++ {
 		wg.Add(1)
-		go start(i, &counter, numRequests, replicas, wg)
+		go start(ctx, c, i, st, numRequests, wg)
 	}
 
 	wg.Wait()
 	latency := time.Since(startTime).Milliseconds()
 
-	persist(numClients, numRequests, int(counter), latency)
+	persist(numClients, numRequests, int(st.success), latency)
 
 	fmt.Println()
-	fmt.Printf("testing is completed (%d out of %d requests)\n", counter, numRequests*numClients)
+	fmt.Printf("testing is completed (%d out of %d requests)\n", st.success, numRequests*numClients)
 	fmt.Printf("total elapsed time: %d ms\n", latency)
+	if st.success > 0 {
+		// compares the fast paxos mode (fast_paxos: true) with the classic mode for the same workload
+		fmt.Printf("decided in fast rounds: %d (%.1f%%)\n", st.fast, 100*float64(st.fast)/float64(st.success))
+		fmt.Printf("average request latency: %.2f ms\n", float64(st.elapsed)/float64(st.success)/float64(time.Millisecond))
+	}
+}
+
+// stats are the counters of the successful requests shared by the clients
+type stats struct {
+	success uint64
+	fast    uint64 // requests decided in fast rounds
+	elapsed int64  // total latency of the successful requests in nanoseconds
 }
 
 func hosts(arg string) []string {
 	list := strings.Split(arg, `,`)
 	if len(list) == 0 {
-		log.Fatalln(`replica list is empty`)
+		log.Fatalln(`seed node list is empty`)
 	}
 
 	return list
 }
 
-func start(id int, countAddr *uint64, numRequests int, replicas []string, wg *sync.WaitGroup) {
+func start(ctx context.Context, c *client.Client, id int, st *stats, numRequests int, wg *sync.WaitGroup) {
+	defer wg.Done()
 	for i := 0; i < numRequests; i++ {
-		replica := replicas[id%len(replicas)]
 		val := strconv.Itoa(rand.Intn(1000))
-
-		fmt.Printf(`client: %d, replica: %d, value: %s`, id, id%len(replicas), val)
-		fmt.Println()
-
-		res, err := httpClient.Post(`http://`+replica+`/replica/request`, `text/plain`, bytes.NewBuffer([]byte(val)))
+		startedAt := time.Now()
+		res, err := c.Write(ctx, val)
 		if err != nil {
-			log.Println(`ERROR: `, err, val)
-			break
+			log.Println(`ERROR: `, err, `of client:`, id, `for val:`, val)
+			if client.IsKind(err, client.KindUnavailable) {
+				break
+			}
+			continue
 		}
 
-		if res.StatusCode != http.StatusOK {
-			log.Println(`Failed Response with code:`, res.StatusCode, `of client:`, id, `for val:`, val)
-			res.Body.Close()
-			continue
+		fmt.Printf("client: %d, replica: %s, value: %s, slot: %d, attempts: %d, fast: %t\n", id, res.Replica, val, res.Slot, res.Attempts, res.Fast)
+		atomic.AddInt64(&st.elapsed, int64(time.Since(startedAt)))
+		atomic.AddUint64(&st.success, 1)
+		if res.Fast {
+			atomic.AddUint64(&st.fast, 1)
 		}
-		res.Body.Close()
-		atomic.AddUint64(countAddr, 1)
 	}
-	wg.Done()
 }
 
 func persist(clients, reqs, success int, latency int64) {
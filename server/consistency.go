@@ -0,0 +1,32 @@
+package server
+
+import (
+	"context"
+	"github.com/go-paxos/config"
+	"github.com/go-paxos/domain"
+	"net/http"
+	"strconv"
+)
+
+// awaitMinSlot blocks until the replica has applied the slot given in the Min-Slot header of the request (if there is
+// one) so that a client observes its own writes and never goes back in the log when it moves between replicas. The
+// status to respond with is returned along with the error if the slot is invalid or not applied in time.
+func (s *server) awaitMinSlot(r *http.Request) (int, error) {
+	val := r.Header.Get(domain.MinSlotHeader)
+	if val == `` {
+		return http.StatusOK, nil
+	}
+
+	slot, err := strconv.Atoi(val)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), config.Get().ConsistencyTimeout.Duration)
+	defer cancel()
+	if err = s.replica.WaitFor(ctx, slot); err != nil {
+		return http.StatusGatewayTimeout, err
+	}
+
+	return http.StatusOK, nil
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"github.com/go-paxos/domain"
+	"net/http"
+)
+
+// withDeadline bounds the context of every request by the timeout header set by the caller, so that a request stops
+// the work done on its behalf once its caller has given up
+func (s *server) withDeadline(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel, err := domain.ExtractTimeout(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(err.Error()))
+			return
+		}
+		defer cancel()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// abortStatus returns the status of a request which failed because its deadline was exceeded (here or at a node it
+// was sent to) or its caller went away, which is zero if the request has failed for another reason
+func abortStatus(ctx context.Context, err error) int {
+	switch {
+	case ctx.Err() == context.DeadlineExceeded, errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case ctx.Err() == context.Canceled:
+		return statusClientClosed
+	}
+
+	return 0
+}
@@ -0,0 +1,15 @@
+package server
+
+import "time"
+
+const (
+	// interval of comments written to idle decision streams so that consumers which have gone away are detected
+	streamHeartbeat = 15 * time.Second
+
+	// status recorded for the requests which were abandoned by their callers (there is no response to write)
+	statusClientClosed = 499
+
+	errDraining  = `node is draining`
+	errStreaming = `response writer does not support streaming`
+	errNegative  = `slot of the stream should not be negative`
+)
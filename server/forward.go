@@ -0,0 +1,36 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-paxos/config"
+	"github.com/go-paxos/domain"
+	"net/http"
+)
+
+// forward relays the request of a replica to the leader which has preempted this leader with the highest ballot when
+// forwarding is enabled, so that a single leader keeps proposing while the others stay out of its way. A request which
+// has already been forwarded, or which the other leader could not be reached for, is proposed by this leader. Returns
+// true if the response has been written.
+func (s *server) forward(ctx context.Context, w http.ResponseWriter, r *http.Request, req domain.Request) bool {
+	if !config.Get().ForwardPreempted || r.Header.Get(domain.ForwardedHeader) != `` {
+		return false
+	}
+
+	leader, ok := s.leader.ForwardTarget()
+	if !ok {
+		return false
+	}
+
+	status, body, err := s.leader.Forward(ctx, leader, req)
+	if err != nil || status >= http.StatusInternalServerError {
+		s.logger.WarnContext(ctx, fmt.Sprintf(`forwarding request to leader %d failed, proposing instead (status: %d): %v`, leader, status, err))
+		return false
+	}
+
+	s.logger.TraceContext(ctx, fmt.Sprintf(`request forwarded to leader %d (status: %d)`, leader, status))
+	w.Header().Set(`Content-Type`, `application/json`)
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+	return true
+}
@@ -4,68 +4,181 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/go-paxos/config"
 	"github.com/go-paxos/domain"
+	"github.com/go-paxos/logger"
+	"github.com/go-paxos/metrics"
 	"github.com/go-paxos/roles"
-	"github.com/google/uuid"
+	"github.com/go-paxos/tracing"
 	"github.com/gorilla/mux"
 	"github.com/tryfix/log"
-	traceableContext "github.com/tryfix/traceable-context"
 	"io/ioutil"
 	"net/http"
-	"os"
 	"strconv"
+	"sync/atomic"
+	"time"
 )
 
 type server struct {
+	id       int
+	role     string
 	hostname string
+	book     *roles.AddressBook
 	leader   *roles.Leader
 	replica  *roles.Replica
+	draining int32 // set to 1 once the node stops admitting new requests
+	inFlight int64 // number of requests which have been admitted and are still being processed
+	limiter  *limiter
+	stop     chan struct{}
+	done     chan struct{} // closed once the node starts shutting down to end long-lived streams
 	logger   log.Logger
 }
 
-func Init(ctx context.Context, port int, leader *roles.Leader, replica *roles.Replica, hostname string, logger log.Logger) {
-	s := &server{leader: leader, replica: replica, hostname: hostname, logger: logger}
+// Init starts serving the http endpoints of the node and blocks until the node is shut down by a signal or by a
+// termination request
+func Init(ctx context.Context, port int, leader *roles.Leader, replica *roles.Replica, self domain.Node, book *roles.AddressBook, logger log.Logger) {
+	s := &server{
+		id:       self.ID,
+		role:     self.Role,
+		hostname: self.Address,
+		book:     book,
+		leader:   leader,
+		replica:  replica,
+		limiter:  newLimiter(),
+		stop:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		logger:   logger,
+	}
 
 	r := mux.NewRouter()
+	r.Use(s.withDeadline)
 	// replica endpoints
 	r.HandleFunc(domain.RequestReplicaEndpoint, s.handleClientRequest).Methods(http.MethodPost)
 	r.HandleFunc(domain.UpdateReplicaEndpoint, s.handleUpdateReplica).Methods(http.MethodPost)
+	r.HandleFunc(domain.LogReplicaEndpoint, s.handleReplicaLog).Methods(http.MethodGet)
+	r.HandleFunc(domain.StreamReplicaEndpoint, s.handleStream).Methods(http.MethodGet)
+	r.HandleFunc(domain.PreferLeaderEndpoint, s.handlePreferLeader).Methods(http.MethodPost)
 
 	// leader endpoints
 	r.HandleFunc(domain.RequestLeaderEndpoint, s.handleReplicaRequest).Methods(http.MethodPost)
 	r.HandleFunc(domain.PrepareEndpoint, s.handlePrepare).Methods(http.MethodPost)
 	r.HandleFunc(domain.AcceptEndpoint, s.handleAccept).Methods(http.MethodPost)
+	r.HandleFunc(domain.AcceptorStateEndpoint, s.handleAcceptorState).Methods(http.MethodGet)
+	r.HandleFunc(domain.DecisionEndpoint, s.handleDecision).Methods(http.MethodPost)
+	r.HandleFunc(domain.DecisionsEndpoint, s.handleDecisions).Methods(http.MethodGet)
+	r.HandleFunc(domain.ChosenEndpoint, s.handleChosen).Methods(http.MethodPost)
 
-	// general termination endpoint
+	// general termination and admin endpoints
 	r.HandleFunc(domain.TermEndpoint, s.terminate).Methods(http.MethodPost)
+	r.HandleFunc(domain.LogLevelEndpoint, s.handleLogLevel).Methods(http.MethodPost)
+	r.HandleFunc(domain.DrainEndpoint, s.handleDrain).Methods(http.MethodPost)
+	r.HandleFunc(domain.AddressBookEndpoint, s.handleGetAddressBook).Methods(http.MethodGet)
+	r.HandleFunc(domain.AddressBookEndpoint, s.handleSetAddress).Methods(http.MethodPost)
+	r.HandleFunc(domain.ReloadEndpoint, s.handleReload).Methods(http.MethodPost)
+	r.HandleFunc(domain.MembershipEndpoint, s.handleGetMembership).Methods(http.MethodGet)
+	r.HandleFunc(domain.MembershipEndpoint, s.handleReconfigure).Methods(http.MethodPost)
+
+	// observability endpoints
+	r.HandleFunc(domain.MetricsEndpoint, s.handleMetrics).Methods(http.MethodGet)
+	r.HandleFunc(domain.HealthEndpoint, s.handleHealth).Methods(http.MethodGet)
+	r.HandleFunc(domain.ReadyEndpoint, s.handleReady).Methods(http.MethodGet)
+	r.HandleFunc(domain.StatusEndpoint, s.handleStatus).Methods(http.MethodGet)
 	s.logger.InfoContext(ctx, `initializing http server`)
-	log.Fatal(http.ListenAndServe(":"+strconv.Itoa(port), r))
+	s.serve(ctx, &http.Server{Addr: ":" + strconv.Itoa(port), Handler: r})
 }
 
 // handleClientRequest handles the client request with a string value in raw body and passes the decoded value to replica
 // to initiate the procedure.
 func (s *server) handleClientRequest(w http.ResponseWriter, r *http.Request) {
-	ctx := traceableContext.WithUUID(uuid.New())
+	ctx, span := tracing.Start(tracing.Extract(r), `replica.client_request`)
+	defer span.End()
+	startedAt := time.Now()
+	status := http.StatusOK
+	defer func() {
+		metrics.ClientRequestDuration.Observe(time.Since(startedAt).Seconds(), strconv.Itoa(status))
+		span.SetAttribute(`http.status_code`, status)
+	}()
+
+	if s.replica == nil {
+		status = http.StatusNotFound
+		w.WriteHeader(status)
+		return
+	}
+
+	if s.replica.IsLearner() {
+		status = http.StatusForbidden
+		w.WriteHeader(status)
+		return
+	}
+
+	if !s.limiter.allow() {
+		status = http.StatusTooManyRequests
+		w.WriteHeader(status)
+		return
+	}
+
+	if !s.admit() {
+		status = http.StatusServiceUnavailable
+		w.WriteHeader(status)
+		return
+	}
+	defer s.release()
+
 	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		s.logger.ErrorContext(ctx, err)
-		w.WriteHeader(http.StatusInternalServerError)
+		status = http.StatusInternalServerError
+		w.WriteHeader(status)
 		return
 	}
 	s.logger.TraceContext(ctx, `client request received`, string(data))
 
-	err = s.replica.HandleRequest(ctx, string(data))
+	if domain.IsReserved(string(data)) {
+		status = http.StatusBadRequest
+		w.WriteHeader(status)
+		return
+	}
+
+	status, err = s.awaitMinSlot(r)
+	if err != nil {
+		s.logger.DebugContext(ctx, err)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	res, err := s.replica.HandleRequest(ctx, r.Header.Get(domain.IdempotencyKeyHeader), string(data))
 	if err != nil {
+		span.SetError(err)
+		if status = abortStatus(ctx, err); status != 0 {
+			s.logger.DebugContext(ctx, err)
+			w.WriteHeader(status)
+			return
+		}
 		s.logger.ErrorContext(ctx, err)
-		w.WriteHeader(http.StatusInternalServerError)
+		status = http.StatusInternalServerError
+		w.WriteHeader(status)
 		return
 	}
-	w.WriteHeader(http.StatusOK)
+
+	w.Header().Set(`Content-Type`, `application/json`)
+	w.Header().Set(domain.SlotHeader, strconv.Itoa(res.SlotID))
+	w.WriteHeader(status)
+	err = json.NewEncoder(w).Encode(&res)
+	if err != nil {
+		s.logger.ErrorContext(ctx, err)
+	}
 }
 
 // handleUpdateReplica updates the state of the current node whenever a consensus is reached and sent by leaders
 func (s *server) handleUpdateReplica(w http.ResponseWriter, r *http.Request) {
-	ctx := traceableContext.WithUUID(uuid.New())
+	ctx, span := tracing.Start(tracing.Extract(r), `replica.update`)
+	defer span.End()
+	if s.replica == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
 	s.logger.TraceContext(ctx, `request for updating replica state is received`)
 	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
@@ -84,6 +197,7 @@ func (s *server) handleUpdateReplica(w http.ResponseWriter, r *http.Request) {
 
 	err = s.replica.Update(ctx, dec)
 	if err != nil {
+		span.SetError(err)
 		s.logger.ErrorContext(ctx, err)
 		w.WriteHeader(http.StatusBadRequest)
 		return
@@ -93,7 +207,19 @@ func (s *server) handleUpdateReplica(w http.ResponseWriter, r *http.Request) {
 
 // handleReplicaRequest handles the request by a replica and forwards to the leader layer to proceed with a proposal
 func (s *server) handleReplicaRequest(w http.ResponseWriter, r *http.Request) {
-	ctx := traceableContext.WithUUID(uuid.New())
+	ctx, span := tracing.Start(tracing.Extract(r), `leader.replica_request`)
+	defer span.End()
+	if s.leader == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if !s.admit() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	defer s.release()
+
 	s.logger.TraceContext(ctx, `replica request for the leader is received`)
 	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
@@ -110,34 +236,28 @@ func (s *server) handleReplicaRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	lastSlot, status := s.leader.ValidateSlot(req.SlotID)
-	var errRes domain.ErrorRes
-	switch status {
-	case roles.FutureSlot:
-		w.WriteHeader(http.StatusTooEarly)
-		errRes.RequestedSlot = req.SlotID
-		errRes.LastSlot = lastSlot
-		s.logger.TraceContext(ctx, fmt.Sprintf(`received a future slot (requested: %d, last slot: %d, val: %s)`, req.SlotID, lastSlot, req.Val))
-
-		err = json.NewEncoder(w).Encode(&errRes)
-		if err != nil {
-			s.logger.ErrorContext(ctx, err)
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-		return
-	case roles.InvalidSlot:
-		s.logger.TraceContext(ctx, fmt.Sprintf(`received an older slot (requested: %d, last slot: %d, val: %s)`, req.SlotID, lastSlot, req.Val))
-		w.WriteHeader(http.StatusBadRequest)
+	if s.forward(ctx, w, r, req) {
 		return
 	}
 
 	dec, ok, err := s.leader.Propose(ctx, req)
 	if err != nil {
+		span.SetError(err)
+		if status := abortStatus(ctx, err); status != 0 {
+			s.logger.DebugContext(ctx, err)
+			w.WriteHeader(status)
+			return
+		}
 		s.logger.ErrorContext(ctx, err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	// a value left to the leader which has preempted this one is forwarded to it
+	if !ok && s.forward(ctx, w, r, req) {
+		return
+	}
+
 	if !ok {
 		s.logger.DebugContext(ctx, `proposed value was not chosen`, req.Val)
 		w.WriteHeader(http.StatusNotAcceptable)
@@ -154,7 +274,13 @@ func (s *server) handleReplicaRequest(w http.ResponseWriter, r *http.Request) {
 
 // handlePrepare handles the prepare request sent by the proposer to an acceptor with initialization of a proposal
 func (s *server) handlePrepare(w http.ResponseWriter, r *http.Request) {
-	ctx := traceableContext.WithUUID(uuid.New())
+	ctx, span := tracing.Start(tracing.Extract(r), `acceptor.prepare`)
+	defer span.End()
+	if s.leader == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
 	s.logger.TraceContext(ctx, `prepare request received by the proposer`)
 	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
@@ -173,8 +299,10 @@ func (s *server) handlePrepare(w http.ResponseWriter, r *http.Request) {
 
 	promise, err := s.leader.HandlePrepare(prop)
 	if err != nil {
-		s.logger.ErrorContext(ctx, err)
-		w.WriteHeader(http.StatusInternalServerError)
+		// the acceptor has refused to consider the proposal
+		s.logger.DebugContext(ctx, err)
+		w.WriteHeader(http.StatusConflict)
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -187,7 +315,13 @@ func (s *server) handlePrepare(w http.ResponseWriter, r *http.Request) {
 
 // handleAccept handles accept requests by the proposer to confirm a proposal
 func (s *server) handleAccept(w http.ResponseWriter, r *http.Request) {
-	ctx := traceableContext.WithUUID(uuid.New())
+	ctx, span := tracing.Start(tracing.Extract(r), `acceptor.accept`)
+	defer span.End()
+	if s.leader == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
 	s.logger.TraceContext(ctx, `accept request received by the proposer`)
 	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
@@ -206,8 +340,10 @@ func (s *server) handleAccept(w http.ResponseWriter, r *http.Request) {
 
 	accept, err := s.leader.HandleAccept(prop)
 	if err != nil {
-		s.logger.ErrorContext(ctx, err)
-		w.WriteHeader(http.StatusInternalServerError)
+		// the acceptor has refused to consider the proposal
+		s.logger.DebugContext(ctx, err)
+		w.WriteHeader(http.StatusConflict)
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -218,17 +354,243 @@ func (s *server) handleAccept(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (s *server) terminate(_ http.ResponseWriter, _ *http.Request) {
-	ctx := traceableContext.WithUUID(uuid.New())
+// handleMetrics exposes the metrics collected by the node in prometheus text format
+func (s *server) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set(`Content-Type`, `text/plain; version=0.0.4; charset=utf-8`)
+	metrics.Write(w)
+}
+
+// handleHealth responds with success as long as the node is able to serve http requests
+func (s *server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleReady responds with success only if the node can take part in reaching consensus. A leader should be able to
+// reach a majority of acceptors whereas a replica should have caught up with received decisions and be able to reach
+// a ready leader.
+func (s *server) handleReady(w http.ResponseWriter, r *http.Request) {
+	ctx := tracing.Extract(r)
+	if atomic.LoadInt32(&s.draining) == 1 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(errDraining))
+		return
+	}
+
+	var err error
+	switch {
+	case s.leader != nil:
+		err = s.leader.Ready(ctx)
+	case s.replica != nil:
+		err = s.replica.Ready(ctx)
+	}
+
+	if err != nil {
+		s.logger.DebugContext(ctx, err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleStatus responds with the current state of the node depending on its role
+func (s *server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := tracing.Extract(r)
+	status := domain.Status{
+		ID:       s.id,
+		Role:     s.role,
+		Hostname: s.hostname,
+		Peers:    s.book.Nodes(),
+		Draining: atomic.LoadInt32(&s.draining) == 1,
+		InFlight: atomic.LoadInt64(&s.inFlight),
+	}
 	if s.leader != nil {
-		s.logger.InfoContext(ctx, fmt.Sprintf(`leader %s is shutting down gracefully`, s.hostname))
-		os.Exit(0)
+		ls := s.leader.Status()
+		status.Leader = &ls
 	}
 
 	if s.replica != nil {
-		s.logger.InfoContext(ctx, fmt.Sprintf(`replica %s is shutting down gracefully`, s.hostname))
-		os.Exit(0)
+		rs := s.replica.Status()
+		status.Replica = &rs
+	}
+
+	w.Header().Set(`Content-Type`, `application/json`)
+	err := json.NewEncoder(w).Encode(&status)
+	if err != nil {
+		s.logger.ErrorContext(ctx, err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// handleReplicaLog responds with the applied log entries of the replica within the slot range given by `from` and
+// `to` query parameters, once the replica has applied the slot in the Min-Slot header
+func (s *server) handleReplicaLog(w http.ResponseWriter, r *http.Request) {
+	ctx := tracing.Extract(r)
+	if s.replica == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	from, err := intParam(r, `from`, 0)
+	if err != nil {
+		s.logger.DebugContext(ctx, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	to, err := intParam(r, `to`, -1)
+	if err != nil {
+		s.logger.DebugContext(ctx, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
 	}
 
-	s.logger.ErrorContext(ctx, `termination failed due to unrecognized role`)
+	status, err := s.awaitMinSlot(r)
+	if err != nil {
+		s.logger.DebugContext(ctx, err)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	w.Header().Set(`Content-Type`, `application/json`)
+	w.Header().Set(domain.SlotHeader, strconv.Itoa(s.replica.Applied()))
+	err = json.NewEncoder(w).Encode(s.replica.Log(from, to))
+	if err != nil {
+		s.logger.ErrorContext(ctx, err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// handlePreferLeader sets the leader to which the replica forwards requests, which is used to transfer leadership
+func (s *server) handlePreferLeader(w http.ResponseWriter, r *http.Request) {
+	ctx := tracing.Extract(r)
+	if s.replica == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var pref domain.LeaderPreference
+	err := json.NewDecoder(r.Body).Decode(&pref)
+	if err != nil {
+		s.logger.ErrorContext(ctx, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err = s.replica.PreferLeader(pref.Leader)
+	if err != nil {
+		s.logger.ErrorContext(ctx, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.logger.InfoContext(ctx, fmt.Sprintf(`preferred leader of replica %s changed to %d`, s.hostname, pref.Leader))
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleGetAddressBook responds with the nodes known to this node along with their current addresses
+func (s *server) handleGetAddressBook(w http.ResponseWriter, r *http.Request) {
+	ctx := tracing.Extract(r)
+	w.Header().Set(`Content-Type`, `application/json`)
+	err := json.NewEncoder(w).Encode(s.book.Nodes())
+	if err != nil {
+		s.logger.ErrorContext(ctx, err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// handleSetAddress updates the address of a node in the address book, which is used when a node is moved
+func (s *server) handleSetAddress(w http.ResponseWriter, r *http.Request) {
+	ctx := tracing.Extract(r)
+	var node domain.Node
+	err := json.NewDecoder(r.Body).Decode(&node)
+	if err != nil {
+		s.logger.ErrorContext(ctx, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err = s.book.Set(node.ID, node.Address)
+	if err != nil {
+		s.logger.ErrorContext(ctx, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.logger.InfoContext(ctx, fmt.Sprintf(`address of node %d changed to %s`, node.ID, node.Address))
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleLogLevel changes the log level of the node at runtime
+func (s *server) handleLogLevel(w http.ResponseWriter, r *http.Request) {
+	ctx := tracing.Extract(r)
+	var lvl domain.LogLevel
+	err := json.NewDecoder(r.Body).Decode(&lvl)
+	if err != nil {
+		s.logger.ErrorContext(ctx, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// the level is changed through the configurations so that a reload restores the level of the configuration file
+	err = config.SetLogLevel(lvl.Level)
+	if err != nil {
+		s.logger.ErrorContext(ctx, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err = logger.SetLevel(config.Get().LogLevel)
+	if err != nil {
+		s.logger.ErrorContext(ctx, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.InfoContext(ctx, fmt.Sprintf(`log level of %s changed to %s`, s.hostname, lvl.Level))
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleDrain stops the node from admitting new client and replica requests while the requests in flight are allowed
+// to complete. Progress of draining can be observed via the status endpoint.
+func (s *server) handleDrain(w http.ResponseWriter, r *http.Request) {
+	ctx := tracing.Extract(r)
+	atomic.StoreInt32(&s.draining, 1)
+	s.logger.InfoContext(ctx, fmt.Sprintf(`%s is draining (in-flight requests: %d)`, s.hostname, atomic.LoadInt64(&s.inFlight)))
+	w.WriteHeader(http.StatusOK)
+}
+
+// admit registers a new request as in flight unless the node is draining
+func (s *server) admit() bool {
+	if atomic.LoadInt32(&s.draining) == 1 {
+		return false
+	}
+	atomic.AddInt64(&s.inFlight, 1)
+	return true
+}
+
+func (s *server) release() {
+	atomic.AddInt64(&s.inFlight, -1)
+}
+
+func intParam(r *http.Request, key string, def int) (int, error) {
+	val := r.URL.Query().Get(key)
+	if val == `` {
+		return def, nil
+	}
+	return strconv.Atoi(val)
+}
+
+// terminate responds to the caller and initiates a graceful shutdown of the node
+func (s *server) terminate(w http.ResponseWriter, r *http.Request) {
+	ctx := tracing.Extract(r)
+	s.logger.InfoContext(ctx, fmt.Sprintf(`termination of %s is requested`, s.hostname))
+	w.WriteHeader(http.StatusAccepted)
+
+	select {
+	case s.stop <- struct{}{}:
+	default:
+		// shutdown has already been initiated
+	}
 }
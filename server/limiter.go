@@ -0,0 +1,46 @@
+package server
+
+import (
+	"github.com/go-paxos/config"
+	"math"
+	"sync"
+	"time"
+)
+
+// limiter is a token bucket which admits client requests according to the rate limit configurations. Configurations
+// are read on every request so that a reloaded rate takes effect immediately.
+type limiter struct {
+	tokens float64
+	last   time.Time
+	lock   *sync.Mutex
+}
+
+func newLimiter() *limiter {
+	return &limiter{lock: &sync.Mutex{}}
+}
+
+func (l *limiter) allow() bool {
+	conf := config.Get()
+	if conf.RateLimit <= 0 {
+		return true
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	burst := float64(conf.RateBurst)
+	if l.last.IsZero() {
+		l.tokens = burst
+	} else {
+		l.tokens = math.Min(burst, l.tokens+now.Sub(l.last).Seconds()*conf.RateLimit)
+	}
+	l.last = now
+
+	if l.tokens < 1 {
+		return false
+	}
+
+	l.tokens--
+	return true
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-paxos/domain"
+	"github.com/go-paxos/tracing"
+	"net/http"
+)
+
+// handleGetMembership responds with the configuration which decides the next slot of the node along with the
+// configurations which are decided but not yet effective
+func (s *server) handleGetMembership(w http.ResponseWriter, r *http.Request) {
+	ctx := tracing.Extract(r)
+	var membership domain.Membership
+	switch {
+	case s.leader != nil:
+		membership = s.leader.Membership()
+	case s.replica != nil:
+		membership = s.replica.Membership()
+	}
+
+	w.Header().Set(`Content-Type`, `application/json`)
+	err := json.NewEncoder(w).Encode(&membership)
+	if err != nil {
+		s.logger.ErrorContext(ctx, err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// handleReconfigure submits a reconfiguration to be decided through the replicated log. Reconfigurations are accepted
+// only by replicas since they are proposed in the same way as client requests.
+func (s *server) handleReconfigure(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(tracing.Extract(r), `replica.reconfigure`)
+	defer span.End()
+	if s.replica == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if s.replica.IsLearner() {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if !s.admit() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	defer s.release()
+
+	var rc domain.Reconfiguration
+	err := json.NewDecoder(r.Body).Decode(&rc)
+	if err != nil {
+		s.logger.ErrorContext(ctx, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err = s.replica.Reconfigure(ctx, rc)
+	if err != nil {
+		span.SetError(err)
+		s.logger.ErrorContext(ctx, err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	s.logger.InfoContext(ctx, fmt.Sprintf(`reconfiguration decided (op: %s, node: %d)`, rc.Op, rc.Node.ID))
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/go-paxos/domain"
+	"github.com/go-paxos/tracing"
+	"io/ioutil"
+	"net/http"
+)
+
+// handleAcceptorState responds with the proposals promised and accepted by the acceptor, and the values it knows to be
+// decided from the requested slot, to a recovering leader
+func (s *server) handleAcceptorState(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(tracing.Extract(r), `acceptor.state`)
+	defer span.End()
+	if s.leader == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	from, err := intParam(r, `from`, 0)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	st := s.leader.AcceptorState(from)
+	w.Header().Set(`Content-Type`, `application/json`)
+	err = json.NewEncoder(w).Encode(&st)
+	if err != nil {
+		s.logger.ErrorContext(ctx, err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// handleDecision handles the decision notices sent by the other leaders to the acceptor
+func (s *server) handleDecision(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(tracing.Extract(r), `acceptor.decision`)
+	defer span.End()
+	if s.leader == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.logger.ErrorContext(ctx, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var dec domain.Decision
+	err = json.Unmarshal(data, &dec)
+	if err != nil {
+		s.logger.ErrorContext(ctx, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.leader.HandleDecision(ctx, dec)
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleChosen handles the values chosen in fast rounds which are announced by the replicas to the leader
+func (s *server) handleChosen(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(tracing.Extract(r), `leader.chosen`)
+	defer span.End()
+	if s.leader == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.logger.ErrorContext(ctx, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var dec domain.Decision
+	err = json.Unmarshal(data, &dec)
+	if err != nil {
+		s.logger.ErrorContext(ctx, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.leader.HandleChosen(ctx, dec)
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleDecisions responds with the values decided from the requested slot which are retained by the acceptor, so
+// that an acceptor or a replica which has missed some of the decisions can catch up
+func (s *server) handleDecisions(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(tracing.Extract(r), `acceptor.decisions`)
+	defer span.End()
+	if s.leader == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	from, err := intParam(r, `from`, 0)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	dl := s.leader.DecisionLog(from)
+	w.Header().Set(`Content-Type`, `application/json`)
+	err = json.NewEncoder(w).Encode(&dl)
+	if err != nil {
+		s.logger.ErrorContext(ctx, err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
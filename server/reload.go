@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-paxos/config"
+	"github.com/go-paxos/domain"
+	"github.com/go-paxos/logger"
+	"github.com/go-paxos/tracing"
+	"net/http"
+	"strings"
+)
+
+// reload applies the runtime-tunable configurations from the configuration file. The reload is refused as a whole if
+// any of the configurations which require a restart has changed.
+func (s *server) reload(ctx context.Context) (domain.ReloadResult, error) {
+	changed, refused, err := config.Reload(s.id)
+	res := domain.ReloadResult{Changed: changed, Refused: refused}
+	if err != nil {
+		res.Error = err.Error()
+		return res, err
+	}
+
+	// the level is applied even if it is unchanged, in case the logger has diverged from the configurations
+	err = logger.SetLevel(config.Get().LogLevel)
+	if err != nil {
+		res.Error = err.Error()
+		return res, err
+	}
+
+	s.logger.InfoContext(ctx, fmt.Sprintf(`configurations reloaded (changed: [%s])`, strings.Join(changed, `, `)))
+	return res, nil
+}
+
+// handleReload reloads the runtime-tunable configurations and responds with the changed and refused configurations
+func (s *server) handleReload(w http.ResponseWriter, r *http.Request) {
+	ctx := tracing.Extract(r)
+	res, err := s.reload(ctx)
+	w.Header().Set(`Content-Type`, `application/json`)
+	if err != nil {
+		s.logger.ErrorContext(ctx, err)
+		if len(res.Refused) > 0 {
+			w.WriteHeader(http.StatusConflict)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}
+
+	err = json.NewEncoder(w).Encode(&res)
+	if err != nil {
+		s.logger.ErrorContext(ctx, err)
+	}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-paxos/config"
+	"github.com/go-paxos/tracing"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+)
+
+// serve runs the http server until a termination signal or request is received and then shuts it down gracefully
+func (s *server) serve(ctx context.Context, srv *http.Server) {
+	// requests in flight are aborted through their contexts if they do not complete within the shutdown timeout
+	baseCtx, abort := context.WithCancel(context.Background())
+	defer abort()
+	srv.BaseContext = func(net.Listener) context.Context { return baseCtx }
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.ListenAndServe() }()
+
+	for {
+		select {
+		case err := <-serveErr:
+			s.logger.FatalContext(ctx, err)
+			return
+		case sig := <-signals:
+			s.logger.InfoContext(ctx, fmt.Sprintf(`received %s signal`, sig))
+			if sig == syscall.SIGHUP {
+				if _, err := s.reload(ctx); err != nil {
+					s.logger.ErrorContext(ctx, err)
+				}
+				continue
+			}
+		case <-s.stop:
+		}
+
+		s.shutdown(ctx, srv, abort)
+		return
+	}
+}
+
+// shutdown stops admitting new requests, waits for the requests in flight (aborting them once the timeout is exceeded)
+// and finally closes the roles so that their state is flushed, along with the spans queued in the exporter
+func (s *server) shutdown(ctx context.Context, srv *http.Server, abort context.CancelFunc) {
+	atomic.StoreInt32(&s.draining, 1)
+	close(s.done)
+	s.logger.InfoContext(ctx, fmt.Sprintf(`%s is shutting down gracefully (in-flight requests: %d)`, s.hostname, atomic.LoadInt64(&s.inFlight)))
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, config.Get().ShutdownTimeout.Duration)
+	defer cancel()
+
+	err := srv.Shutdown(shutdownCtx)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			s.logger.ErrorContext(ctx, fmt.Sprintf(`aborting %d in-flight requests as shutdown timeout exceeded`, atomic.LoadInt64(&s.inFlight)))
+		} else {
+			s.logger.ErrorContext(ctx, err)
+		}
+		abort()
+		_ = srv.Close()
+	}
+
+	if s.leader != nil {
+		s.leader.Close(ctx)
+	}
+
+	if s.replica != nil {
+		s.replica.Close(ctx)
+	}
+
+	// spans of the last requests are still queued in the exporter
+	flushCtx, cancelFlush := context.WithTimeout(ctx, config.Get().ShutdownTimeout.Duration)
+	defer cancelFlush()
+	if err = tracing.Flush(flushCtx); err != nil {
+		s.logger.ErrorContext(ctx, err)
+	}
+
+	s.logger.InfoContext(ctx, fmt.Sprintf(`%s is shut down`, s.hostname))
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-paxos/config"
+	"github.com/go-paxos/metrics"
+	"github.com/go-paxos/tracing"
+	"net/http"
+	"strconv"
+)
+
+// handleStream streams the applied log entries of the replica in slot order as server-sent events, starting from the
+// `from` query parameter or from the slot following the Last-Event-ID header when a consumer reconnects. Entries are
+// read from the log only as fast as the consumer receives them, so a slow consumer falls behind without entries being
+// buffered for it.
+func (s *server) handleStream(w http.ResponseWriter, r *http.Request) {
+	ctx := tracing.Extract(r)
+	if s.replica == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.logger.ErrorContext(ctx, errStreaming)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	from, err := intParam(r, `from`, 0)
+	if err != nil {
+		s.logger.DebugContext(ctx, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if id := r.Header.Get(`Last-Event-ID`); id != `` {
+		last, err := strconv.Atoi(id)
+		if err != nil {
+			s.logger.DebugContext(ctx, err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if last < 0 {
+			s.logger.DebugContext(ctx, fmt.Sprintf(`%s (Last-Event-ID: %d)`, errNegative, last))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		from = last + 1
+	}
+
+	if from < 0 {
+		s.logger.DebugContext(ctx, fmt.Sprintf(`%s (from: %d)`, errNegative, from))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set(`Content-Type`, `text/event-stream`)
+	w.Header().Set(`Cache-Control`, `no-cache`)
+	w.Header().Set(`Connection`, `keep-alive`)
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// the stream is closed when the consumer goes away or when the node starts shutting down
+	streamCtx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.done:
+			cancel()
+		case <-streamCtx.Done():
+		}
+	}()
+
+	metrics.StreamSubscribers.Add(1)
+	defer metrics.StreamSubscribers.Add(-1)
+	s.logger.DebugContext(ctx, fmt.Sprintf(`decision stream opened from slot %d`, from))
+
+	for {
+		waitCtx, waitCancel := context.WithTimeout(streamCtx, streamHeartbeat)
+		entries, err := s.replica.Entries(waitCtx, from, config.Get().StreamBatchSize)
+		waitCancel()
+		if err != nil {
+			if streamCtx.Err() != nil {
+				s.logger.DebugContext(ctx, fmt.Sprintf(`decision stream closed at slot %d`, from))
+				return
+			}
+
+			if _, err = fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+			continue
+		}
+
+		for _, entry := range entries {
+			data, err := json.Marshal(entry)
+			if err != nil {
+				s.logger.ErrorContext(ctx, err)
+				return
+			}
+
+			if _, err = fmt.Fprintf(w, "id: %d\nevent: decision\ndata: %s\n\n", entry.SlotID, data); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+		from += len(entries)
+	}
+}
@@ -0,0 +1,5 @@
+package tracing
+
+const (
+	errCollector = `received non-2xx code from trace collector`
+)
@@ -0,0 +1,282 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-paxos/logger"
+	"github.com/tryfix/log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	scopeName     = `go-paxos`
+	queueSize     = 1024
+	batchSize     = 100
+	flushInterval = time.Second
+
+	statusCodeOk    = 1
+	statusCodeError = 2
+	spanKindServer  = 1
+)
+
+type exporter struct {
+	service   string
+	file      *os.File
+	collector string
+	spans     chan *Span
+	flushes   chan chan struct{} // requests to export the queued spans right away, closed once exported
+	client    *http.Client
+	logger    log.Logger
+}
+
+var (
+	current *exporter
+	once    = &sync.Once{}
+)
+
+// Init enables exporting of spans in OpenTelemetry (OTLP/JSON) format to a local file, a collector or both. Spans are
+// only propagated and not recorded if neither of the destinations is configured.
+func Init(ctx context.Context, service, filePath, collectorURL string, logger log.Logger) error {
+	if filePath == `` && collectorURL == `` {
+		return nil
+	}
+
+	// the file is only opened by the first call so that a repeated call does not leak a file handle
+	var err error
+	once.Do(func() {
+		e := &exporter{
+			service:   service,
+			collector: collectorURL,
+			spans:     make(chan *Span, queueSize),
+			flushes:   make(chan chan struct{}),
+			client:    &http.Client{Timeout: 5 * time.Second},
+			logger:    logger,
+		}
+
+		if filePath != `` {
+			e.file, err = os.OpenFile(filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+			if err != nil {
+				return
+			}
+		}
+
+		current = e
+		go e.run(ctx)
+		logger.InfoContext(ctx, `span exporter initialized`)
+	})
+
+	return err
+}
+
+// export queues the span without blocking the caller and drops it if the exporter falls behind
+func export(span *Span) {
+	if current == nil {
+		return
+	}
+
+	select {
+	case current.spans <- span:
+	default:
+	}
+}
+
+func (e *exporter) run(ctx context.Context) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+	for {
+		var flushed chan struct{}
+		select {
+		case span := <-e.spans:
+			batch = append(batch, span)
+			if len(batch) < batchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		case flushed = <-e.flushes:
+			batch = append(batch, e.drain()...)
+		}
+
+		if len(batch) > 0 {
+			if err := e.flush(batch); err != nil {
+				e.logger.ErrorContext(ctx, err)
+			}
+		}
+		batch = nil
+
+		if flushed != nil {
+			close(flushed)
+		}
+	}
+}
+
+// drain returns the spans which are queued without waiting for more
+func (e *exporter) drain() []*Span {
+	var spans []*Span
+	for {
+		select {
+		case span := <-e.spans:
+			spans = append(spans, span)
+		default:
+			return spans
+		}
+	}
+}
+
+// Flush exports the spans which are queued so far and waits until they are exported or the context is done, so that
+// the spans of a node which is shutting down are not lost
+func Flush(ctx context.Context) error {
+	if current == nil {
+		return nil
+	}
+
+	flushed := make(chan struct{})
+	select {
+	case current.flushes <- flushed:
+	case <-ctx.Done():
+		return logger.ErrorWithLine(ctx.Err())
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return logger.ErrorWithLine(ctx.Err())
+	}
+}
+
+func (e *exporter) flush(batch []*Span) error {
+	data, err := json.Marshal(e.encode(batch))
+	if err != nil {
+		return logger.ErrorWithLine(err)
+	}
+
+	if e.file != nil {
+		if _, err = e.file.Write(append(data, '\n')); err != nil {
+			return logger.ErrorWithLine(err)
+		}
+	}
+
+	if e.collector != `` {
+		res, err := e.client.Post(e.collector, `application/json`, bytes.NewBuffer(data))
+		if err != nil {
+			return logger.ErrorWithLine(err)
+		}
+		res.Body.Close()
+
+		if res.StatusCode/100 != 2 {
+			return logger.ErrorWithLine(errors.New(fmt.Sprintf(`%s (status: %d)`, errCollector, res.StatusCode)))
+		}
+	}
+
+	return nil
+}
+
+/* OTLP/JSON encoding */
+
+type attribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpSpan struct {
+	TraceID      string      `json:"traceId"`
+	SpanID       string      `json:"spanId"`
+	ParentSpanID string      `json:"parentSpanId,omitempty"`
+	Name         string      `json:"name"`
+	Kind         int         `json:"kind"`
+	Start        string      `json:"startTimeUnixNano"`
+	End          string      `json:"endTimeUnixNano"`
+	Attributes   []attribute `json:"attributes"`
+	Status       struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	} `json:"status"`
+}
+
+type scopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type resourceSpans struct {
+	Resource struct {
+		Attributes []attribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type otlpTraces struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+func (e *exporter) encode(batch []*Span) otlpTraces {
+	var scope scopeSpans
+	scope.Scope.Name = scopeName
+	for _, span := range batch {
+		scope.Spans = append(scope.Spans, encodeSpan(span))
+	}
+
+	var rs resourceSpans
+	rs.Resource.Attributes = []attribute{newAttribute(`service.name`, e.service)}
+	rs.ScopeSpans = []scopeSpans{scope}
+
+	return otlpTraces{ResourceSpans: []resourceSpans{rs}}
+}
+
+func encodeSpan(span *Span) otlpSpan {
+	span.lock.Lock()
+	defer span.lock.Unlock()
+
+	s := otlpSpan{
+		TraceID:      hex.EncodeToString(span.traceID[:]),
+		SpanID:       span.id,
+		ParentSpanID: span.parentID,
+		Name:         span.name,
+		Kind:         spanKindServer,
+		Start:        strconv.FormatInt(span.start.UnixNano(), 10),
+		End:          strconv.FormatInt(span.end.UnixNano(), 10),
+		Attributes:   []attribute{},
+	}
+
+	var keys []string
+	for k := range span.attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s.Attributes = append(s.Attributes, newAttribute(k, span.attributes[k]))
+	}
+
+	s.Status.Code = statusCodeOk
+	if span.failed {
+		s.Status.Code = statusCodeError
+		s.Status.Message = span.attributes[`error`]
+	}
+
+	return s
+}
+
+func newAttribute(key, val string) attribute {
+	var a attribute
+	a.Key = key
+	a.Value.StringValue = val
+	return a
+}
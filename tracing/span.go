@@ -0,0 +1,170 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/google/uuid"
+	traceableContext "github.com/tryfix/traceable-context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Header is the W3C trace context header used to carry the trace across nodes
+const Header = `traceparent`
+
+type spanKey struct{}
+
+// Span records the timing of a single operation within a trace
+type Span struct {
+	name       string
+	traceID    uuid.UUID
+	id         string
+	parentID   string
+	start      time.Time
+	end        time.Time
+	attributes map[string]string
+	failed     bool
+	lock       *sync.Mutex
+}
+
+// Start creates a child span of the span in given context (if any) and returns a context carrying the new span. The
+// trace id is taken from the traceable context so that log lines and spans of a request share the same id.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	traceID := traceableContext.FromContext(ctx)
+	if traceID == uuid.Nil {
+		traceID = uuid.New()
+		ctx = traceableContext.FromContextWithUUID(ctx, traceID)
+	}
+
+	span := &Span{
+		name:       name,
+		traceID:    traceID,
+		id:         newSpanID(),
+		start:      time.Now(),
+		attributes: map[string]string{},
+		lock:       &sync.Mutex{},
+	}
+
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok {
+		span.parentID = parent.id
+	} else if parentID, ok := ctx.Value(remoteParentKey{}).(string); ok {
+		span.parentID = parentID
+	}
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SetAttribute attaches a key value pair to the span
+func (s *Span) SetAttribute(key string, val interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.attributes[key] = fmt.Sprint(val)
+}
+
+// SetError marks the span as failed along with the error message
+func (s *Span) SetError(err error) {
+	if err == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.failed = true
+	s.attributes[`error`] = err.Error()
+}
+
+// End completes the span and hands it over to the exporter
+func (s *Span) End() {
+	s.lock.Lock()
+	s.end = time.Now()
+	s.lock.Unlock()
+	export(s)
+}
+
+type remoteParentKey struct{}
+
+// Extract builds a traceable context for an incoming request by continuing the trace found in its headers. A new trace
+// is started if the request does not carry a valid trace header. The context is derived from the context of the
+// request so that it carries the deadline and cancellation of the request.
+func Extract(r *http.Request) context.Context {
+	traceID, parentID, ok := parse(r.Header.Get(Header))
+	if !ok {
+		return traceableContext.FromContextWithUUID(r.Context(), uuid.New())
+	}
+
+	return context.WithValue(traceableContext.FromContextWithUUID(r.Context(), traceID), remoteParentKey{}, parentID)
+}
+
+// Detach returns a context which continues the trace of given context without its deadline and cancellation, so that
+// the work which outlives a request is still traced as a part of it
+func Detach(ctx context.Context) context.Context {
+	traceID := traceableContext.FromContext(ctx)
+	if traceID == uuid.Nil {
+		return traceableContext.WithUUID(uuid.New())
+	}
+
+	detached := traceableContext.WithUUID(traceID)
+	if span, ok := ctx.Value(spanKey{}).(*Span); ok {
+		return context.WithValue(detached, remoteParentKey{}, span.id)
+	}
+
+	if parentID, ok := ctx.Value(remoteParentKey{}).(string); ok {
+		return context.WithValue(detached, remoteParentKey{}, parentID)
+	}
+
+	return detached
+}
+
+// Inject sets the trace header of an outgoing request using the trace and the current span in given context, or the
+// remote parent of a detached context. The header is not set without a span to refer to as the parent.
+func Inject(ctx context.Context, req *http.Request) {
+	traceID := traceableContext.FromContext(ctx)
+	if traceID == uuid.Nil {
+		return
+	}
+
+	var spanID string
+	if span, ok := ctx.Value(spanKey{}).(*Span); ok {
+		spanID = span.id
+	} else if parentID, ok := ctx.Value(remoteParentKey{}).(string); ok {
+		spanID = parentID
+	} else {
+		return
+	}
+
+	req.Header.Set(Header, fmt.Sprintf(`00-%s-%s-01`, hex.EncodeToString(traceID[:]), spanID))
+}
+
+// parse decodes a header in the format of `version-traceid-spanid-flags`
+func parse(header string) (traceID uuid.UUID, spanID string, ok bool) {
+	parts := strings.Split(header, `-`)
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return uuid.Nil, ``, false
+	}
+
+	raw, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return uuid.Nil, ``, false
+	}
+
+	traceID, err = uuid.FromBytes(raw)
+	if err != nil || traceID == uuid.Nil {
+		return uuid.Nil, ``, false
+	}
+
+	if _, err = hex.DecodeString(parts[2]); err != nil {
+		return uuid.Nil, ``, false
+	}
+
+	return traceID, parts[2], true
+}
+
+func newSpanID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"context"
+	"github.com/google/uuid"
+	traceableContext "github.com/tryfix/traceable-context"
+	"net/http"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		valid  bool
+	}{
+		{name: `valid`, header: `00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01`, valid: true},
+		{name: `empty`, header: ``},
+		{name: `missing flags`, header: `00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7`},
+		{name: `short trace id`, header: `00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01`},
+		{name: `zero trace id`, header: `00-00000000000000000000000000000000-00f067aa0ba902b7-01`},
+		{name: `non-hex span id`, header: `00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902zz-01`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, ok := parse(test.header); ok != test.valid {
+				t.Errorf(`expected valid: %t, found: %t`, test.valid, ok)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	traceID := uuid.New()
+	ctx, span := Start(traceableContext.WithUUID(traceID), `test.outgoing`)
+
+	out, _ := http.NewRequest(http.MethodGet, `http://localhost`, nil)
+	Inject(ctx, out)
+
+	// the receiving node continues the trace with the span of the sender as the parent of its spans
+	in, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, `http://localhost`, nil)
+	in.Header.Set(Header, out.Header.Get(Header))
+	_, child := Start(Extract(in), `test.incoming`)
+	if child.traceID != traceID {
+		t.Errorf(`expected trace id: %s, found: %s`, traceID, child.traceID)
+	}
+	if child.parentID != span.id {
+		t.Errorf(`expected parent span: %s, found: %s`, span.id, child.parentID)
+	}
+
+	// a detached context keeps referring to the same parent when the trace is injected again
+	again, _ := http.NewRequest(http.MethodGet, `http://localhost`, nil)
+	Inject(Detach(ctx), again)
+	if again.Header.Get(Header) != out.Header.Get(Header) {
+		t.Errorf(`expected header: %s, found: %s`, out.Header.Get(Header), again.Header.Get(Header))
+	}
+
+	// no header is injected without a span to refer to as the parent
+	orphan, _ := http.NewRequest(http.MethodGet, `http://localhost`, nil)
+	Inject(traceableContext.WithUUID(traceID), orphan)
+	if header := orphan.Header.Get(Header); header != `` {
+		t.Errorf(`expected no header, found: %s`, header)
+	}
+}